@@ -0,0 +1,67 @@
+// Package cache stores the serialized results of the HTTP handlers, either in memory or in a server
+// speaking the Redis protocol so that several replicas can share them.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Get for keys that are missing or expired
+var ErrNotFound = errors.New("cache: key not found")
+
+// Cache stores values for a limited time. Implementations are safe for concurrent use.
+type Cache interface {
+	// Get returns the value of key or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key for ttl, which must be positive
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key if it exists
+	Delete(ctx context.Context, key string) error
+}
+
+// prefixed namespaces the keys of a Cache
+type prefixed struct {
+	cache  Cache
+	prefix string
+}
+
+// Prefix returns a Cache storing its keys in c under prefix, so handlers can share a backend
+// without their keys colliding
+func Prefix(c Cache, prefix string) Cache {
+	return &prefixed{c, prefix + "/"}
+}
+
+func (p *prefixed) Get(ctx context.Context, key string) ([]byte, error) {
+	return p.cache.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixed) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return p.cache.Set(ctx, p.prefix+key, value, ttl)
+}
+
+func (p *prefixed) Delete(ctx context.Context, key string) error {
+	return p.cache.Delete(ctx, p.prefix+key)
+}
+
+// GetJSON decodes the JSON value of key into v
+func GetJSON(ctx context.Context, c Cache, key string, v interface{}) error {
+	data, err := c.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// SetJSON stores v encoded as JSON under key for ttl
+func SetJSON(ctx context.Context, c Cache, key string, v interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.Set(ctx, key, data, ttl)
+}
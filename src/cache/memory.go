@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process LRU cache bounded by the number of entries and by the size of their keys
+// and values. The least recently used entries are evicted first, expired entries once they are read
+// or evicted.
+type Memory struct {
+	maxEntries int
+	maxBytes   int64
+
+	lock    sync.Mutex
+	bytes   int64
+	entries map[string]*list.Element
+	// recency holds *memoryEntry, most recently used first
+	recency *list.List
+}
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+// NewMemory returns an empty cache holding at most maxEntries entries of maxBytes in total, a limit
+// that is 0 or less is not enforced
+func NewMemory(maxEntries int, maxBytes int64) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		recency:    list.New(),
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	entry := element.Value.(*memoryEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		m.remove(element)
+		return nil, ErrNotFound
+	}
+
+	m.recency.MoveToFront(element)
+	return entry.value, nil
+}
+
+// Set stores value, which must not be modified afterwards. Values larger than the byte limit are not
+// stored.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	entry := &memoryEntry{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+
+	if m.maxBytes > 0 && entry.size() > m.maxBytes {
+		return nil
+	}
+
+	m.entries[key] = m.recency.PushFront(entry)
+	m.bytes += entry.size()
+
+	for (m.maxEntries > 0 && len(m.entries) > m.maxEntries) || (m.maxBytes > 0 && m.bytes > m.maxBytes) {
+		m.remove(m.recency.Back())
+	}
+
+	return nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if element, ok := m.entries[key]; ok {
+		m.remove(element)
+	}
+
+	return nil
+}
+
+// Len returns the number of entries and their size in bytes, including expired ones not yet evicted
+func (m *Memory) Len() (int, int64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return len(m.entries), m.bytes
+}
+
+func (m *Memory) remove(element *list.Element) {
+	entry := m.recency.Remove(element).(*memoryEntry)
+	delete(m.entries, entry.key)
+	m.bytes -= entry.size()
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryEvictsByEntries(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(3, 0)
+
+	for i := 0; i < 5; i++ {
+		if err := memory.Set(ctx, fmt.Sprintf("key%d", i), []byte("value"), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if entries, _ := memory.Len(); entries != 3 {
+		t.Errorf("%d entries, want 3", entries)
+	}
+	for i := 0; i < 5; i++ {
+		_, err := memory.Get(ctx, fmt.Sprintf("key%d", i))
+		if evicted := i < 2; evicted != errors.Is(err, ErrNotFound) {
+			t.Errorf("Get of key%d = %v, evicted %t", i, err, evicted)
+		}
+	}
+}
+
+func TestMemoryEvictsByBytes(t *testing.T) {
+	ctx := context.Background()
+	// Entries of a 4 byte key and a 6 byte value take 10 bytes
+	memory := NewMemory(0, 25)
+
+	for i := 0; i < 3; i++ {
+		if err := memory.Set(ctx, fmt.Sprintf("key%d", i), []byte("value!"), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if entries, size := memory.Len(); entries != 2 || size != 20 {
+		t.Errorf("%d entries of %d bytes, want 2 of 20", entries, size)
+	}
+	if _, err := memory.Get(ctx, "key0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of the oldest key = %v, want %v", err, ErrNotFound)
+	}
+
+	// Values larger than the limit are not stored and don't evict anything
+	if err := memory.Set(ctx, "large", make([]byte, 100), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.Get(ctx, "large"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a value above the limit = %v, want %v", err, ErrNotFound)
+	}
+	if entries, _ := memory.Len(); entries != 2 {
+		t.Errorf("%d entries after storing a value above the limit, want 2", entries)
+	}
+}
+
+func TestMemoryGetMarksRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(2, 0)
+
+	memory.Set(ctx, "a", []byte("value"), time.Minute)
+	memory.Set(ctx, "b", []byte("value"), time.Minute)
+
+	// Reading a makes b the least recently used entry
+	if _, err := memory.Get(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	memory.Set(ctx, "c", []byte("value"), time.Minute)
+
+	if _, err := memory.Get(ctx, "a"); err != nil {
+		t.Errorf("Get of the recently read key = %v", err)
+	}
+	if _, err := memory.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of the least recently used key = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryExpiry(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(0, 0)
+
+	memory.Set(ctx, "short", []byte("value"), 10*time.Millisecond)
+	memory.Set(ctx, "long", []byte("value"), time.Minute)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := memory.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of an expired key = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := memory.Get(ctx, "long"); err != nil {
+		t.Errorf("Get of a key that has not expired = %v", err)
+	}
+	// Expired entries are removed once they are read
+	if entries, _ := memory.Len(); entries != 1 {
+		t.Errorf("%d entries, want 1", entries)
+	}
+}
+
+func TestMemoryOverwrite(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemory(0, 0)
+
+	memory.Set(ctx, "key", []byte("old"), time.Minute)
+	memory.Set(ctx, "key", []byte("newer"), time.Minute)
+
+	value, err := memory.Get(ctx, "key")
+	if err != nil || string(value) != "newer" {
+		t.Errorf("Get = %q, %v, want %q", value, err, "newer")
+	}
+	if entries, size := memory.Len(); entries != 1 || size != 8 {
+		t.Errorf("%d entries of %d bytes, want 1 of 8", entries, size)
+	}
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultRedisTimeout bounds each command when Redis.Timeout is not set
+	DefaultRedisTimeout = time.Second
+	// redisIdleConns is how many connections are kept open between commands
+	redisIdleConns = 16
+)
+
+// Redis stores entries in a server speaking the Redis protocol (RESP), such as Redis, Valkey or
+// KeyDB, so they survive restarts and are shared between replicas
+type Redis struct {
+	// Address is the host:port of the server
+	Address string
+	// Password is sent with AUTH if set
+	Password string
+	// DB is selected on every new connection if not 0
+	DB int
+	// Timeout bounds each command, DefaultRedisTimeout if 0
+	Timeout time.Duration
+
+	idle chan *redisConn
+}
+
+type redisConn struct {
+	net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// NewRedis returns a cache backed by the server at address, connections are opened as needed
+func NewRedis(address string, password string, db int) *Redis {
+	return &Redis{
+		Address:  address,
+		Password: password,
+		DB:       db,
+		idle:     make(chan *redisConn, redisIdleConns),
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, []byte("GET"), []byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := reply.(type) {
+	case nil:
+		return nil, ErrNotFound
+	case []byte:
+		return value, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %v to GET", reply)
+	}
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// PX takes whole milliseconds and rejects 0
+	milliseconds := ttl.Milliseconds()
+	if milliseconds < 1 {
+		milliseconds = 1
+	}
+
+	_, err := r.do(ctx, []byte("SET"), []byte(key), value, []byte("PX"), []byte(strconv.FormatInt(milliseconds, 10)))
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, []byte("DEL"), []byte(key))
+	return err
+}
+
+// Close closes the idle connections
+func (r *Redis) Close() error {
+	for {
+		select {
+		case conn := <-r.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (r *Redis) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return DefaultRedisTimeout
+}
+
+// do sends a command on an idle or new connection and returns the reply, error replies are returned
+// as RedisError
+func (r *Redis) do(ctx context.Context, args ...[]byte) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout())
+	defer cancel()
+
+	conn, reused, err := r.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, args...)
+	if err != nil && reused && ctx.Err() == nil {
+		// Idle connections may have been closed by the server, the command is sent again on a new one
+		conn.Close()
+		if conn, err = r.dial(ctx); err != nil {
+			return nil, err
+		}
+		reply, err = conn.do(ctx, args...)
+	}
+	if err != nil {
+		// The connection may be in the middle of a reply
+		conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.idle <- conn:
+	default:
+		conn.Close()
+	}
+
+	if redisErr, ok := reply.(RedisError); ok {
+		return nil, redisErr
+	}
+	return reply, nil
+}
+
+// conn returns an idle connection, reporting that it was reused, or a new one
+func (r *Redis) conn(ctx context.Context) (*redisConn, bool, error) {
+	select {
+	case conn := <-r.idle:
+		return conn, true, nil
+	default:
+		conn, err := r.dial(ctx)
+		return conn, false, err
+	}
+}
+
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{
+		Conn:   netConn,
+		reader: bufio.NewReader(netConn),
+		writer: bufio.NewWriter(netConn),
+	}
+
+	if r.Password != "" {
+		if err := conn.expectOK(ctx, []byte("AUTH"), []byte(r.Password)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if r.DB != 0 {
+		if err := conn.expectOK(ctx, []byte("SELECT"), []byte(strconv.Itoa(r.DB))); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *redisConn) do(ctx context.Context, args ...[]byte) (interface{}, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := c.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeCommand(c.writer, args...); err != nil {
+		return nil, err
+	}
+
+	return readReply(c.reader)
+}
+
+func (c *redisConn) expectOK(ctx context.Context, args ...[]byte) error {
+	reply, err := c.do(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	if redisErr, ok := reply.(RedisError); ok {
+		return redisErr
+	}
+	if reply != "OK" {
+		return fmt.Errorf("redis: unexpected reply %v to %s", reply, args[0])
+	}
+
+	return nil
+}
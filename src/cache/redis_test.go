@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"torch/src/cache/resptest"
+)
+
+func newTestRedis(t *testing.T, password string, db int) (*Redis, *resptest.Server) {
+	t.Helper()
+
+	server, err := resptest.NewServer(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	redis := NewRedis(server.Addr(), password, db)
+	t.Cleanup(func() { redis.Close() })
+
+	return redis, server
+}
+
+func TestRedisSetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	redis, server := newTestRedis(t, "", 0)
+
+	if _, err := redis.Get(ctx, "status/example.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get of a missing key = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := redis.Set(ctx, "status/example.com", []byte(`{"online":true}`), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	value, err := redis.Get(ctx, "status/example.com")
+	if err != nil || string(value) != `{"online":true}` {
+		t.Fatalf("Get = %q, %v, want %q", value, err, `{"online":true}`)
+	}
+	if count := server.Len(); count != 1 {
+		t.Errorf("server holds %d keys, want 1", count)
+	}
+
+	if err := redis.Delete(ctx, "status/example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := redis.Get(ctx, "status/example.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestRedisExpiry(t *testing.T) {
+	ctx := context.Background()
+	redis, _ := newTestRedis(t, "", 0)
+
+	// Durations below a millisecond are sent as the smallest PX Redis accepts
+	if err := redis.Set(ctx, "short", []byte("value"), time.Microsecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := redis.Set(ctx, "long", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := redis.Get(ctx, "short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of an expired key = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := redis.Get(ctx, "long"); err != nil {
+		t.Errorf("Get of a key that has not expired = %v", err)
+	}
+}
+
+func TestRedisAuth(t *testing.T) {
+	ctx := context.Background()
+	redis, server := newTestRedis(t, "secret", 0)
+
+	if err := redis.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := NewRedis(server.Addr(), "wrong", 0)
+	defer wrong.Close()
+	var redisErr RedisError
+	if _, err := wrong.Get(ctx, "key"); !errors.As(err, &redisErr) {
+		t.Errorf("Get with a wrong password = %v, want a RedisError", err)
+	}
+
+	anonymous := NewRedis(server.Addr(), "", 0)
+	defer anonymous.Close()
+	if _, err := anonymous.Get(ctx, "key"); !errors.As(err, &redisErr) {
+		t.Errorf("Get without a password = %v, want a RedisError", err)
+	}
+}
+
+func TestRedisSelect(t *testing.T) {
+	ctx := context.Background()
+	redis, server := newTestRedis(t, "", 3)
+
+	if err := redis.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewRedis(server.Addr(), "", 0)
+	defer other.Close()
+	if _, err := other.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get from another database = %v, want %v", err, ErrNotFound)
+	}
+
+	same := NewRedis(server.Addr(), "", 3)
+	defer same.Close()
+	if _, err := same.Get(ctx, "key"); err != nil {
+		t.Errorf("Get from the same database = %v", err)
+	}
+
+	invalid := NewRedis(server.Addr(), "", 99)
+	defer invalid.Close()
+	if _, err := invalid.Get(ctx, "key"); err == nil {
+		t.Error("Get from a database out of range succeeded")
+	}
+}
+
+func TestRedisRetriesDroppedIdleConnection(t *testing.T) {
+	ctx := context.Background()
+	redis, server := newTestRedis(t, "secret", 2)
+
+	if err := redis.Set(ctx, "key", []byte("value"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	// The connection of Set is idle now, the server closing it must not fail the next command
+	server.DropConnections()
+
+	value, err := redis.Get(ctx, "key")
+	if err != nil || string(value) != "value" {
+		t.Fatalf("Get after the connection was dropped = %q, %v, want %q", value, err, "value")
+	}
+}
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxBulkLength bounds the bulk strings read from the server, which is the limit of Redis itself
+const maxBulkLength = 512 << 20
+
+// RedisError is an error reply of the server
+type RedisError string
+
+func (e RedisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// writeCommand writes a command as an array of bulk strings
+func writeCommand(w *bufio.Writer, args ...[]byte) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n", len(arg)); err != nil {
+			return err
+		}
+		if _, err := w.Write(arg); err != nil {
+			return err
+		}
+		if _, err := w.WriteString("\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// readReply reads a reply, returning a string for simple strings, RedisError for errors, an int64 for
+// integers, a []byte for bulk strings, an []interface{} for arrays and nil for null replies
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 1 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return RedisError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		length, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		if length > maxBulkLength {
+			return nil, fmt.Errorf("redis: bulk string length %d exceeds limit", length)
+		}
+
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return data[:length], nil
+	case '*':
+		count, err := strconv.Atoi(string(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+
+		elements := make([]interface{}, 0)
+		for i := 0; i < count; i++ {
+			element, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, element)
+		}
+		return elements, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
+
+// readLine reads a line terminated by CRLF without the terminator
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, errors.New("redis: reply line too long")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errors.New("redis: malformed reply line")
+	}
+
+	return line[:len(line)-2], nil
+}
@@ -0,0 +1,284 @@
+// Package resptest provides an in-process server speaking the subset of the Redis protocol used by
+// cache.Redis, for testing without a Redis installation, in the manner of net/http/httptest.
+package resptest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server answers PING, AUTH, SELECT, GET, SET (with EX or PX), DEL and FLUSHALL. Each connection
+// starts in database 0.
+type Server struct {
+	password string
+	listener net.Listener
+
+	lock sync.Mutex
+	// entries holds the keys of each database
+	entries map[int]map[string]entry
+	conns   map[net.Conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// databases is the number of databases, as in the default Redis configuration
+const databases = 16
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewServer starts a server on a random local port, which requires AUTH with password before other
+// commands unless it is empty
+func NewServer(password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		password: password,
+		listener: listener,
+		entries:  make(map[int]map[string]entry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Len returns the number of keys that have not expired in all databases
+func (s *Server) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	count := 0
+	for _, entries := range s.entries {
+		for _, entry := range entries {
+			if time.Now().Before(entry.expiresAt) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// DropConnections closes the open connections while the server keeps accepting new ones, like a
+// server closing idle clients
+func (s *Server) DropConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the server and closes all connections
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lock.Unlock()
+
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+	writer := bufio.NewWriter(conn)
+	session := &session{authenticated: s.password == ""}
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		reply := s.execute(args, session)
+		if _, err := writer.WriteString(reply); err != nil {
+			return
+		}
+		if err := writer.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// session is the state of a connection
+type session struct {
+	authenticated bool
+	db            int
+}
+
+// execute runs a command and returns the encoded reply
+func (s *Server) execute(args [][]byte, session *session) string {
+	if len(args) < 1 {
+		return "-ERR empty command\r\n"
+	}
+
+	command := strings.ToUpper(string(args[0]))
+	if command == "AUTH" {
+		if len(args) != 2 || string(args[1]) != s.password {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		session.authenticated = true
+		return "+OK\r\n"
+	}
+	if !session.authenticated {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entries := s.entries[session.db]
+	if entries == nil {
+		entries = make(map[string]entry)
+		s.entries[session.db] = entries
+	}
+
+	switch {
+	case command == "PING":
+		return "+PONG\r\n"
+	case command == "SELECT" && len(args) == 2:
+		db, err := strconv.Atoi(string(args[1]))
+		if err != nil || db < 0 || db >= databases {
+			return "-ERR DB index is out of range\r\n"
+		}
+		session.db = db
+		return "+OK\r\n"
+	case command == "FLUSHALL":
+		s.entries = make(map[int]map[string]entry)
+		return "+OK\r\n"
+	case command == "GET" && len(args) == 2:
+		entry, ok := entries[string(args[1])]
+		if !ok || !time.Now().Before(entry.expiresAt) {
+			delete(entries, string(args[1]))
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(entry.value), entry.value)
+	case command == "SET" && len(args) == 5:
+		amount, err := strconv.ParseInt(string(args[4]), 10, 64)
+		if err != nil || amount <= 0 {
+			return "-ERR invalid expire time in 'set' command\r\n"
+		}
+
+		var ttl time.Duration
+		switch strings.ToUpper(string(args[3])) {
+		case "EX":
+			ttl = time.Duration(amount) * time.Second
+		case "PX":
+			ttl = time.Duration(amount) * time.Millisecond
+		default:
+			return "-ERR syntax error\r\n"
+		}
+
+		entries[string(args[1])] = entry{
+			value:     append([]byte(nil), args[2]...),
+			expiresAt: time.Now().Add(ttl),
+		}
+		return "+OK\r\n"
+	case command == "DEL" && len(args) >= 2:
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := entries[string(key)]; ok {
+				delete(entries, string(key))
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s' or wrong number of arguments\r\n", args[0])
+	}
+}
+
+// readCommand reads an array of bulk strings
+func readCommand(r *bufio.Reader) ([][]byte, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") || !strings.HasSuffix(line, "\r\n") {
+		return nil, errors.New("expected an array of bulk strings")
+	}
+
+	count, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid array length %q", line)
+	}
+
+	args := make([][]byte, count)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") || !strings.HasSuffix(line, "\r\n") {
+			return nil, errors.New("expected a bulk string")
+		}
+
+		length, err := strconv.Atoi(line[1 : len(line)-2])
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("invalid bulk string length %q", line)
+		}
+
+		data := make([]byte, length+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		args[i] = data[:length]
+	}
+
+	return args, nil
+}
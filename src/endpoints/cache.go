@@ -0,0 +1,51 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"torch/src/cache"
+)
+
+func mustNewCache(address string) cache.Cache {
+	store, err := newCache(address)
+	if err != nil {
+		panic(fmt.Sprintf("cache: %s", err))
+	}
+
+	return store
+}
+
+// newCache returns the in-memory cache if address is empty, otherwise the Redis server at address in
+// the format redis://[:password@]host[:port][/db]
+func newCache(address string) (cache.Cache, error) {
+	if address == "" {
+		return cache.NewMemory(cacheMaxEntries, cacheMaxBytes), nil
+	}
+
+	redisURL, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if redisURL.Scheme != "redis" || redisURL.Hostname() == "" {
+		return nil, fmt.Errorf("invalid cache %q, expected redis://[:password@]host[:port][/db]", address)
+	}
+
+	host := redisURL.Host
+	if redisURL.Port() == "" {
+		host = net.JoinHostPort(redisURL.Hostname(), "6379")
+	}
+
+	db := 0
+	if path := strings.TrimPrefix(redisURL.Path, "/"); path != "" {
+		if db, err = strconv.Atoi(path); err != nil {
+			return nil, fmt.Errorf("invalid cache database %q", path)
+		}
+	}
+
+	password, _ := redisURL.User.Password()
+	return cache.NewRedis(host, password, db), nil
+}
@@ -0,0 +1,53 @@
+package endpoints
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"torch/src/pkg/torch"
+)
+
+func mustNewDialer(proxy string, source string) torch.Dialer {
+	dialer, err := newDialer(proxy, source)
+	if err != nil {
+		panic(fmt.Sprintf("dialer: %s", err))
+	}
+
+	return dialer
+}
+
+func newDialer(proxy string, source string) (torch.Dialer, error) {
+	var sourceIP net.IP
+	if source != "" {
+		if sourceIP = net.ParseIP(source); sourceIP == nil {
+			return nil, fmt.Errorf("invalid source address %q", source)
+		}
+	}
+
+	// Without either the defaults of torch are used, including the platform's DNS resolver
+	if proxy == "" && sourceIP == nil {
+		return nil, nil
+	}
+
+	direct := torch.NewDialer(sourceIP)
+	if proxy == "" {
+		return direct, nil
+	}
+
+	proxyURL, err := url.Parse(proxy)
+	if err != nil {
+		return nil, err
+	}
+	if proxyURL.Scheme != "socks5" || proxyURL.Port() == "" {
+		return nil, fmt.Errorf("invalid proxy %q, expected socks5://[user:password@]host:port", proxy)
+	}
+
+	password, _ := proxyURL.User.Password()
+	return &torch.SOCKS5Dialer{
+		Address:  proxyURL.Host,
+		Username: proxyURL.User.Username(),
+		Password: password,
+		Forward:  direct,
+	}, nil
+}
@@ -0,0 +1,23 @@
+package endpoints
+
+import (
+	"torch/src/pkg/torch"
+
+	"github.com/gin-gonic/gin"
+)
+
+func DnsHandler(c *gin.Context) {
+	host, err := torch.ParseHost(c.Param("host"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := torch.LookupHost(c.Request.Context(), host, options())
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, result)
+}
@@ -1,289 +1,98 @@
 package endpoints
 
 import (
-	"bytes"
-	"encoding/binary"
-	"encoding/json"
+	"context"
 	"fmt"
 	"net"
+	"net/url"
 	"strconv"
-	"strings"
 	"time"
 
+	"torch/src/pkg/torch"
 	"torch/src/structs"
-	"torch/src/utils"
-
-	"github.com/gin-gonic/gin"
 )
 
-func FetchJava(host string, port uint16) (*structs.JavaStatus, error) {
-	originalHost, originalPort := host, port
-
-	if port == 25565 {
-		srv, _ := srv(host)
-		if srv != nil {
-			host, port = srv.Target, srv.Port
-		}
-	}
+var javaProber = &probe[*structs.JavaStatus]{
+	name:        "java",
+	route:       "/status/java",
+	defaultPort: 25565,
+	cacheTime:   statusCacheTime,
+	maxStale:    statusMaxStale,
+	params:      []string{"forge", "srv", "proxy_protocol"},
+	run:         fetchJava,
+}
 
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(int(port))), statusTimeout)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
+func init() {
+	Register(javaProber)
+}
 
-	if err = conn.SetDeadline(time.Now().Add(statusTimeout)); err != nil {
-		return nil, err
+func fetchJava(ctx context.Context, target torch.Target, query url.Values) (*structs.JavaStatus, error) {
+	opts := options()
+	opts.ForgeMarker = query.Get("forge")
+	opts.SrvMode = query.Get("srv")
+	if !torch.IsValidForgeMarker(opts.ForgeMarker) {
+		return nil, badParam("forge must be one of fml, fml2 or fml3")
 	}
-
-	if err = sendHandshake(conn, host, port); err != nil {
-		return nil, err
+	if !torch.IsValidSrvMode(opts.SrvMode) {
+		return nil, badParam("srv must be one of auto, force or disable")
 	}
-
-	if err = sendStatusRequest(conn); err != nil {
+	if err := setProxyProtocol(&opts, query); err != nil {
 		return nil, err
 	}
 
-	rawJavaResponse, err := readStatusResponse(conn)
+	status, err := torch.FetchJava(ctx, target.Host, target.Port, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	pingStart := time.Now()
-	pingPayload := time.Now().UnixNano()
-
-	if err = sendPing(conn, pingPayload); err != nil {
-		return nil, err
-	}
-
-	if err = readPong(conn, pingPayload); err != nil {
-		return nil, err
-	}
-
-	return createJavaStatus(originalHost, originalPort, host, port, rawJavaResponse, pingStart), nil
-}
-
-func sendHandshake(conn net.Conn, host string, port uint16) error {
-	buf := &bytes.Buffer{}
-
-	if _, err := utils.WriteVarInt(0x00, buf); err != nil {
-		return err
-	}
-
-	if _, err := utils.WriteVarInt(47, buf); err != nil {
-		return err
-	}
-
-	if err := utils.WriteString(host, buf); err != nil {
-		return err
-	}
-
-	if err := binary.Write(buf, binary.BigEndian, port); err != nil {
-		return err
-	}
-
-	if _, err := utils.WriteVarInt(1, buf); err != nil {
-		return err
+	if status.Icon == "" {
+		status.Icon = defaultIcon
 	}
+	status.ExpiresAt = time.Now().Add(statusCacheTime)
 
-	return utils.WritePacket(buf, conn)
+	return status, nil
 }
 
-func sendStatusRequest(conn net.Conn) error {
-	buf := &bytes.Buffer{}
-
-	if _, err := utils.WriteVarInt(0x00, buf); err != nil {
-		return err
+// setProxyProtocol reads the PROXY header version from proxy_protocol. The announced client address
+// is proxySource, which is server configuration so callers can't make servers see arbitrary addresses.
+func setProxyProtocol(opts *torch.Options, query url.Values) error {
+	opts.ProxyProtocol = query.Get("proxy_protocol")
+	if !torch.IsValidProxyProtocol(opts.ProxyProtocol) {
+		return badParam("proxy_protocol must be one of v1 or v2")
 	}
 
-	return utils.WritePacket(buf, conn)
+	opts.ProxySource = proxySource
+	return nil
 }
 
-func readStatusResponse(conn net.Conn) (structs.RawJavaStatus, error) {
-	var rawJavaResponse structs.RawJavaStatus
-
-	_, _, err := utils.ReadVarInt(conn)
-	if err != nil {
-		return rawJavaResponse, err
-	}
-
-	packetId, _, err := utils.ReadVarInt(conn)
-	if err != nil {
-		return rawJavaResponse, err
-	}
-
-	if packetId != 0x00 {
-		return rawJavaResponse, fmt.Errorf("unexpected packet ID (expected 0x00, got 0x%02x)", packetId)
-	}
-
-	response, err := utils.ReadString(conn)
+func mustParseProxySource(source string) *net.TCPAddr {
+	addr, err := parseProxySource(source)
 	if err != nil {
-		return rawJavaResponse, err
+		panic(fmt.Sprintf("proxy source: %s", err))
 	}
 
-	err = json.Unmarshal([]byte(response), &rawJavaResponse)
-	return rawJavaResponse, err
+	return addr
 }
 
-func sendPing(conn net.Conn, pingPayload int64) error {
-	buf := &bytes.Buffer{}
-
-	if _, err := utils.WriteVarInt(0x01, buf); err != nil {
-		return err
+// parseProxySource parses an IP address with an optional port, returning nil if source is empty
+func parseProxySource(source string) (*net.TCPAddr, error) {
+	if source == "" {
+		return nil, nil
 	}
 
-	if err := binary.Write(buf, binary.BigEndian, pingPayload); err != nil {
-		return err
-	}
-
-	return utils.WritePacket(buf, conn)
-}
-
-func readPong(conn net.Conn, pingPayload int64) error {
-	_, _, err := utils.ReadVarInt(conn)
-	if err != nil {
-		return err
+	if ip := net.ParseIP(source); ip != nil {
+		return &net.TCPAddr{IP: ip}, nil
 	}
 
-	packetId, _, err := utils.ReadVarInt(conn)
+	host, port, err := net.SplitHostPort(source)
 	if err != nil {
-		return err
-	}
-
-	if packetId != 0x01 {
-		return fmt.Errorf("unexpected packet ID (expected 0x01, got 0x%02x)", packetId)
-	}
-
-	var returnedPayload int64
-	if err := binary.Read(conn, binary.BigEndian, &returnedPayload); err != nil {
-		return err
-	}
-
-	if returnedPayload != pingPayload {
-		return fmt.Errorf("unexpected payload (expected %d, got %d)", pingPayload, returnedPayload)
-	}
-
-	return nil
-}
-
-func createJavaStatus(originalHost string, originalPort uint16, host string, port uint16, rawJavaResponse structs.RawJavaStatus, pingStart time.Time) *structs.JavaStatus {
-	// Process data
-	description := structs.Parse(rawJavaResponse.Description)
-
-	samplePlayers := make([]structs.Player, 0)
-
-	if rawJavaResponse.Players.Sample != nil {
-		for _, player := range rawJavaResponse.Players.Sample {
-			name := structs.Parse(player.Name)
-			samplePlayers = append(samplePlayers, structs.Player{
-				ID:   player.ID,
-				Name: *name,
-			})
-		}
+		return nil, fmt.Errorf("invalid address %q, expected an IP address with an optional port", source)
 	}
-
-	versionText := structs.Parse(rawJavaResponse.Version.Name)
-
-	var srv *structs.SrvRecord
-	if originalHost == host {
-		srv = nil
-	} else {
-		srv = &structs.SrvRecord{
-			Host: host,
-			Port: port,
-		}
-	}
-
-	if rawJavaResponse.Favicon == "" {
-		rawJavaResponse.Favicon = defaultIcon
-	}
-
-	result := &structs.JavaStatus{
-		Host: originalHost,
-		Port: originalPort,
-		Version: structs.Version{
-			Name:     versionText,
-			Protocol: rawJavaResponse.Version.Protocol,
-		},
-		Players: structs.Players{
-			Max:    rawJavaResponse.Players.Max,
-			Online: rawJavaResponse.Players.Online,
-			Sample: samplePlayers,
-		},
-		Description: description,
-		Icon:        rawJavaResponse.Favicon,
-		SrvRecord:   srv,
-		Latency:     time.Duration(time.Since(pingStart).Milliseconds()),
-		ModInfo:     nil,
-		ObtainedAt:  time.Now(),
-		ExpiresAt:   time.Now().Add(time.Duration(statusCacheTime)),
-	}
-
-	if len(rawJavaResponse.ModInfo.Type) > 0 {
-		mods := make([]structs.Mod, 0)
-		for _, mod := range rawJavaResponse.ModInfo.List {
-			mods = append(mods, structs.Mod{
-				ID:      mod.ModID,
-				Version: mod.Version,
-			})
-		}
-		result.ModInfo = &structs.ModInfo{
-			Type:    rawJavaResponse.ModInfo.Type,
-			ModList: mods,
-		}
-	}
-
-	if rawJavaResponse.ForgeData.Mods != nil {
-		mods := make([]structs.Mod, 0)
-		for _, mod := range rawJavaResponse.ForgeData.Mods {
-			mods = append(mods, structs.Mod{
-				ID:      mod.ModID,
-				Version: mod.Version,
-			})
-		}
-		result.ModInfo = &structs.ModInfo{
-			Type:    "forge",
-			ModList: mods,
-		}
-	}
-	return result
-}
-
-func FetchJavaHandler(c *gin.Context) {
-	ip := c.Param("ip")
-	var port int
-
-	if strings.Contains(ip, ":") {
-		split := strings.Split(ip, ":")
-		ip = split[0]
-		p, err := strconv.Atoi(split[1])
-		if err != nil {
-			port = 25565
-		}
-		port = p
-	} else {
-		port = 25565
-	}
-
-	uintPort := uint16(port)
-
-	cacheKey := fmt.Sprintf("%s:%d", ip, port)
-	data, err := javaCache.Value(cacheKey)
-	if err == nil {
-		c.JSON(200, data.Data().(*structs.JavaStatus))
-		return
-	}
-
-	fetchedData, err := FetchJava(ip, uintPort)
-	if err != nil {
-		c.JSON(200, structs.OfflineServer{
-			Offline: true,
-			Host:    ip,
-			Port:    uintPort,
-		})
-		return
+	ip := net.ParseIP(host)
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if ip == nil || err != nil {
+		return nil, fmt.Errorf("invalid address %q, expected an IP address with an optional port", source)
 	}
 
-	javaCache.Add(cacheKey, statusCacheTime, fetchedData)
-	c.JSON(200, fetchedData)
+	return &net.TCPAddr{IP: ip, Port: int(portNumber)}, nil
 }
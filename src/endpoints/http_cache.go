@@ -0,0 +1,132 @@
+package endpoints
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"torch/src/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheStatusCarrier is implemented by results embedding structs.CacheMeta
+type cacheStatusCarrier interface {
+	GetCacheStatus() string
+	SetCacheStatus(status string)
+}
+
+// respondCached writes payload as JSON with Cache-Control for the time left until expiresAt, a weak ETag
+// and Last-Modified from obtainedAt, answering matching conditional requests with 304. Zero times
+// are left out of the headers.
+func respondCached(c *gin.Context, payload interface{}, obtainedAt time.Time, expiresAt time.Time) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	etag, err := payloadETag(payload)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+
+	respondData(c, "application/json; charset=utf-8", body, etag, obtainedAt, expiresAt)
+}
+
+// respondData writes body with the caching headers of respondCached
+func respondData(c *gin.Context, contentType string, body []byte, etag string, obtainedAt time.Time, expiresAt time.Time) {
+	header := c.Writer.Header()
+	header.Set("ETag", etag)
+	if expiresAt.IsZero() {
+		header.Set("Cache-Control", "no-cache")
+	} else {
+		maxAge := int(time.Until(expiresAt).Seconds())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		header.Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	}
+	if !obtainedAt.IsZero() {
+		header.Set("Last-Modified", obtainedAt.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, obtainedAt) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(200, contentType, body)
+}
+
+// dataETag returns a strong ETag for data
+func dataETag(data []byte) string {
+	hash := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// payloadETag hashes the JSON of payload with the cache states of its results cleared, so the ETag
+// stays the same for as long as the same results are served. As the bodies served under it differ in
+// their cache states the ETag is weak (RFC 9110 8.8.1).
+func payloadETag(payload interface{}) (string, error) {
+	carriers := make([]cacheStatusCarrier, 0)
+	if carrier, ok := payload.(cacheStatusCarrier); ok {
+		carriers = append(carriers, carrier)
+	}
+	if status, ok := payload.(*structs.Status); ok {
+		if status.Java != nil {
+			carriers = append(carriers, status.Java)
+		}
+		if status.Bedrock != nil {
+			carriers = append(carriers, status.Bedrock)
+		}
+	}
+
+	cacheStatuses := make([]string, len(carriers))
+	for i, carrier := range carriers {
+		cacheStatuses[i] = carrier.GetCacheStatus()
+		carrier.SetCacheStatus("")
+	}
+
+	data, err := json.Marshal(payload)
+
+	for i, carrier := range carriers {
+		carrier.SetCacheStatus(cacheStatuses[i])
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return "W/" + dataETag(data), nil
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since if there is none (RFC 9110 13.2.2)
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// If-None-Match uses the weak comparison
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	ifModifiedSince, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates have a resolution of one second
+	return !lastModified.Truncate(time.Second).After(ifModifiedSince)
+}
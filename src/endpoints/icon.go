@@ -1,58 +1,105 @@
 package endpoints
 
 import (
-	"fmt"
+	"context"
 	"strconv"
-	"strings"
 	"time"
+
+	"torch/src/cache"
+	"torch/src/pkg/torch"
 	"torch/src/structs"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Icon response formats selected with ?format=
+const (
+	iconFormatJSON = "json"
+	iconFormatPNG  = "png"
+)
+
 func IconHandler(c *gin.Context) {
-	ip := c.Param("ip")
-	var port int
-
-	if strings.Contains(ip, ":") {
-		split := strings.Split(ip, ":")
-		ip = split[0]
-		p, err := strconv.Atoi(split[1])
-		if err != nil {
-			port = 25565
+	target, ok := targetParam(c, "ip", 25565)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", iconFormatJSON)
+	if format != iconFormatJSON && format != iconFormatPNG {
+		c.JSON(400, gin.H{"error": "format must be one of json or png"})
+		return
+	}
+
+	size := 0
+	if rawSize := c.Query("size"); rawSize != "" {
+		if format != iconFormatPNG {
+			c.JSON(400, gin.H{"error": "size requires format=png"})
+			return
+		}
+
+		var err error
+		size, err = strconv.Atoi(rawSize)
+		if err != nil || size < 1 || size > maxIconSize {
+			c.JSON(400, gin.H{"error": "size must be between 1 and " + strconv.Itoa(maxIconSize)})
+			return
 		}
-		port = p
-	} else {
-		port = 25565
 	}
 
-	uintPort := uint16(port)
+	icon := iconCached(c.Request.Context(), target)
 
-	cacheKey := fmt.Sprintf("%s:%d", ip, port)
-	data, err := iconCache.Value(cacheKey)
-	if err == nil {
-		c.JSON(200, data.Data().(*structs.Icon))
+	if format == iconFormatPNG {
+		respondIconImage(c, icon, size)
 		return
 	}
 
-	javaStatus, err := FetchJava(ip, uintPort)
+	respondCached(c, icon, icon.ObtainedAt, icon.ExpiresAt)
+}
+
+// iconCached returns the cached icon of the target, fetching it if there is none. Servers that can't
+// be reached get the default icon, which is not cached.
+func iconCached(ctx context.Context, target torch.Target) *structs.Icon {
+	cacheKey := target.String()
+
+	cached := &structs.Icon{}
+	if err := cache.GetJSON(ctx, iconCache, cacheKey, cached); err == nil {
+		return cached
+	}
+
+	fetchedIcon, err := torch.FetchIcon(ctx, target.Host, target.Port, options())
 	if err != nil {
-		c.JSON(200, structs.Icon{
-			Host: ip,
-			Port: uintPort,
+		return &structs.Icon{
+			Host: target.Host,
+			Port: target.Port,
 			Data: defaultIcon,
-		})
-		return
+		}
 	}
 
-	icon := structs.Icon{
-		Host: ip,
-		Port: uintPort,
-		Data: javaStatus.Icon,
+	if fetchedIcon.Data == "" {
+		fetchedIcon.Data = defaultIcon
+	}
+
+	icon := &structs.Icon{
+		Host:       target.Host,
+		Port:       target.Port,
+		Data:       fetchedIcon.Data,
 		ObtainedAt: time.Now(),
-		ExpiresAt: time.Now().Add(time.Duration(iconCacheTime)),
+		ExpiresAt:  time.Now().Add(iconCacheTime),
+	}
+
+	cache.SetJSON(ctx, iconCache, cacheKey, icon, iconCacheTime)
+	return icon
+}
+
+// respondIconImage serves the icon as a PNG, replacing icons that can't be decoded with the default one
+func respondIconImage(c *gin.Context, icon *structs.Icon, size int) {
+	data, err := encodeIconPNG(icon.Data, size)
+	if err != nil {
+		data, err = encodeIconPNG(defaultIcon, size)
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
 	}
 
-	iconCache.Add(ip, iconCacheTime, icon)
-	c.JSON(200, icon)
+	respondData(c, "image/png", data, dataETag(data), icon.ObtainedAt, icon.ExpiresAt)
 }
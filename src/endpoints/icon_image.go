@@ -0,0 +1,80 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"image/png"
+	"strings"
+)
+
+const (
+	// maxIconSize is the largest size an icon can be scaled to with ?size=
+	maxIconSize = 512
+	// maxIconDimension bounds the decoded icons, vanilla servers send 64x64
+	maxIconDimension = 1024
+)
+
+// decodeIcon decodes a base64 data URI holding a PNG or JPEG image
+func decodeIcon(dataURI string) (image.Image, error) {
+	metadata, encoded, ok := strings.Cut(strings.TrimPrefix(dataURI, "data:"), ",")
+	if !ok || !strings.HasPrefix(dataURI, "data:") || !strings.HasSuffix(metadata, ";base64") {
+		return nil, errors.New("icon is not a base64 data URI")
+	}
+
+	// Some servers wrap the base64 data over several lines
+	encoded = strings.NewReplacer("\n", "", "\r", "").Replace(encoded)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width > maxIconDimension || config.Height > maxIconDimension {
+		return nil, fmt.Errorf("icon of %dx%d exceeds %dx%d", config.Width, config.Height, maxIconDimension, maxIconDimension)
+	}
+
+	icon, _, err := image.Decode(bytes.NewReader(data))
+	return icon, err
+}
+
+// scaleNearest scales src to size x size without smoothing, which keeps pixel art sharp
+func scaleNearest(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+
+	for y := 0; y < size; y++ {
+		srcY := bounds.Min.Y + y*bounds.Dy()/size
+		for x := 0; x < size; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/size
+			dst.Set(x, y, src.At(srcX, srcY))
+		}
+	}
+
+	return dst
+}
+
+// encodeIconPNG converts the icon in dataURI to PNG, scaled to size x size unless size is 0
+func encodeIconPNG(dataURI string, size int) ([]byte, error) {
+	icon, err := decodeIcon(dataURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if size > 0 {
+		icon = scaleNearest(icon, size)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, icon); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"torch/src/pkg/torch"
+	"torch/src/structs"
+)
+
+func init() {
+	Register(&probe[*structs.LoginCheck]{
+		name:        "login",
+		route:       "/login-check",
+		defaultPort: 25565,
+		cacheTime:   loginCacheTime,
+		maxStale:    loginMaxStale,
+		params:      []string{"protocol", "proxy_protocol"},
+		run:         checkLogin,
+	})
+}
+
+func checkLogin(ctx context.Context, target torch.Target, query url.Values) (*structs.LoginCheck, error) {
+	// Without an explicit protocol the server's own version is used
+	opts := options()
+	if rawProtocol := query.Get("protocol"); rawProtocol != "" {
+		protocolVersion, err := strconv.ParseUint(rawProtocol, 10, 31)
+		if err != nil {
+			return nil, badParam("protocol must be a non-negative integer")
+		}
+		opts.ProtocolVersion = int(protocolVersion)
+	}
+	if err := setProxyProtocol(&opts, query); err != nil {
+		return nil, err
+	}
+
+	result, err := torch.CheckLogin(ctx, target.Host, target.Port, opts)
+	if errors.Is(err, torch.ErrLoginUnsupported) {
+		// This is a fact about the server, cached like any other result
+		result, err = &structs.LoginCheck{
+			Host:       target.Host,
+			Port:       target.Port,
+			Protocol:   opts.ProtocolVersion,
+			Result:     structs.LoginResultUnsupported,
+			ObtainedAt: time.Now(),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.ExpiresAt = time.Now().Add(loginCacheTime)
+
+	return result, nil
+}
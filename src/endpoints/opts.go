@@ -1,29 +1,97 @@
 package endpoints
 
 import (
+	"fmt"
+	"os"
 	"time"
 
-	"github.com/muesli/cache2go"
+	"torch/src/cache"
+	"torch/src/pkg/torch"
 )
 
 var (
 
-	// SRV
-	srvCache     = cache2go.Cache("srv")
-	srvCacheTime = 30 * time.Minute
+	// Outgoing connections go through the SOCKS5 proxy in TORCH_SOCKS5_PROXY
+	// (socks5://[user:password@]host:port) if set and are bound to TORCH_SOURCE_ADDRESS if set
+	dialer = mustNewDialer(os.Getenv("TORCH_SOCKS5_PROXY"), os.Getenv("TORCH_SOURCE_ADDRESS"))
 
-	// Status
-	statusCacheTime = 30 * time.Second
-	statusTimeout   = 2 * time.Second
+	// PROXY protocol headers requested with proxy_protocol announce TORCH_PROXY_SOURCE (an IP address
+	// with an optional port) as the client if set, otherwise the local address of the connection
+	proxySource = mustParseProxySource(os.Getenv("TORCH_PROXY_SOURCE"))
 
-	// Bedrock
-	bedrockCache = cache2go.Cache("bedrock")
+	// DNS, the server is read from TORCH_DNS_SERVER (host or host:port) and queried over
+	// TORCH_DNS_NETWORK (udp or tcp). The system resolver is used if it is not set. Queries go through
+	// dialer like the pings, over TCP when a SOCKS5 proxy is set.
+	resolver = torch.NewResolver(os.Getenv("TORCH_DNS_SERVER"), os.Getenv("TORCH_DNS_NETWORK"), dialer)
 
-	// Java
-	javaCache = cache2go.Cache("java")
+	// Cache, results are kept in memory unless TORCH_CACHE_URL is the Redis server to share them
+	// through (redis://[:password@]host[:port][/db]). The memory cache holds up to cacheMaxEntries
+	// entries of cacheMaxBytes in total.
+	cacheStore      = mustNewCache(os.Getenv("TORCH_CACHE_URL"))
+	cacheMaxEntries = 10000
+	cacheMaxBytes   = int64(64 << 20)
+
+	// SRV, hosts without a record are cached for srvMissingCacheTime
+	srvCache            = cache.Prefix(cacheStore, "srv")
+	srvMissingCacheTime = 5 * time.Second
+
+	// Status, a failed lookup is retried statusRetries times after statusRetryBackoff and Bedrock pings
+	// are resent every statusRetransmitInterval. Latency is measured over statusPingSamples pings.
+	// Expired results are served for up to statusMaxStale while they are refreshed.
+	statusCacheTime          = 30 * time.Second
+	statusMaxStale           = 2 * time.Minute
+	statusTimeout            = 2 * time.Second
+	statusConnectTimeout     = 2 * time.Second
+	statusReadTimeout        = 2 * time.Second
+	statusRetries            = 1
+	statusRetryBackoff       = 250 * time.Millisecond
+	statusRetransmitInterval = 500 * time.Millisecond
+	statusPingSamples        = 3
+
+	// Offline records are cached for offlineCacheTime, doubling with every failed lookup in a row up
+	// to offlineMaxCacheTime
+	offlineCacheTime    = 5 * time.Second
+	offlineMaxCacheTime = 5 * time.Minute
+
+	// Login
+	loginCacheTime = 5 * time.Minute
+	loginMaxStale  = 30 * time.Minute
 
 	// Icon
-	iconCache     = cache2go.Cache("icon")
+	iconCache     = cache.Prefix(cacheStore, "icon")
 	iconCacheTime = 30 * time.Minute
 	defaultIcon   = "data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQABAAD/2wBDAAMCAgICAgMCAgIDAwMDBAYEBAQEBAgGBgUGCQgKCgkICQkKDA8MCgsOCwkJDRENDg8QEBEQCgwSExIQEw8QEBD/wAALCABAAEABAREA/8QAHQAAAAcBAQEAAAAAAAAAAAAAAAIDBAUGBwgBCf/EADUQAAEDAgQEBAMHBQEAAAAAAAECAwQFEQAGEiEHEzFhCCJBURQjoRUyQnGRscEkUlNigfD/2gAIAQEAAD8A3+wwLDAsMCwwLDAsMCwwLDBtJwNPtiIjZwynNrTuW4eY6Y9VWQS5CbkpU6mwBPlB9LjEvp74Gnvgae+HlPpFQqhWIMfmcsgKOoAC/Trieh0prL8dT1bpTMpbiwG7KCtG24N/fFb0H2OGlWn0+j02RU6tNZhRGEFTr7qwlLY6XJPf62x87a2s8PM9O1HK+b4dTEJ8TIM6M0oLClKJGttY2Um3mBuNx1vjd6D4rszoyvBZnZbYlVdLa/ipcpwtpcOolKg22Bp2I6+2J6t+LqlUzK65qMuaK2eWGIzrpXHdNxzCVJGpNvQHr740PgVxfo3FXJtRzLmKVEy+ujuBM1xy6Y2lX3VJWo9drEHcEj3xpUGoltrnUuoIfjSAFodju3bdT+FSSOo74UL0x83Slaz7hJVgkp6kx4EeqJq8YxZenkuOLCAvV0sSd8Zb4kK3HpXDZ6A4wl/7bkIiIN9kBPzSvbY20Cw6b443XT2nZhUmGht9JOpZBsD2/j88GW3LhWeN1AnzJt0x5UmY9UgrhyG7pcTdCgkEpV6Ef+9cXSVWY83hkchxmnKVFaccqbaqcwn+re0ABl9JUkLTqA0r3KSeih07H8PuVGcmcLKRTadmT7djyEfGtyXUoPK5qUqLCLE2ShWoW6g32GNGMib/AJykf62GOIMr8WIfEigxsvsrciyYJXITEcF0X02IbO907HbY7nFK4uZ/rSFt0CqOao1AC4kWNZK0pdXutSiNid7X9ALYz6g15+toESS7p0m50gDydQbn2/jHma8wSqWxHbYLjbyioKdt5bja2/axwXLdXNQYV94OtglSzbdVidiOnp1xOZuzPBy61HahOxnpSQ2lbHMspA0g3IHfD/hx4ns1ZAmmFSzF+zJCtbrMkKW2F+4H4bnYqG+/rjoHxE+Ix3LeTqDGyBWYqKvX2Ey3ZMOWhxcFISkqbKCCQVFVgVWsEnY45Fy9BcpUpqQZ7ja2ybKaGnR3uDfp6Yn6uqS7ECmChfxAPzCLJUPe3v674j8vUp+mtjlKQ4p5fLv0366b/kMNq5FdrYbEpt1hhFndlDzg+W5/S2FabEh0mO5FbLinVWV90WKbX3PUWwzzRRkz2lux2CqYo6UupVYJSCCCRfpY/XEKxTIMOrMGpZdkNwlIUhSQ6ohbhULKBvf+647YTlwqzLqC1LU3y3EgIfWoBopsAkAnobAAfljpSNxmy5T3viWqDIcW2W3W9T7KNCkruoaU3BBAthVnjvQIpcCaG8ptUpTzaTJbFkqQpJSdvUKAv6W74RPiBjRyyum0hhEllxhWqQ8lSTpQUOXSkA+ZNhsdrX9cMpvHJoVOBJj09pNNioLTkRfKUXBzCtNnNJI07Cxvf3wuOOmWrAHKyflr5remW2kFdz18m22kW6eXvis5t40VGqIbiUKEzAZQ2ptSy426p1JSdjdAtZRWdvfsMVGi16TCW0+3T48ppHNKGlv2AUtGkK99gB22xIp4jZ+QhDfxba0hOneOwrYX6eXvijKqb51JQpoOX3+X1GPRVVhIUXWttj8ofXBVVc7FT6exLQt+tsEcrcgJs063vubtW/fBW6q4o7SiogbjQCP2wdurPlRIfjrsdkhsbf8AbWwJNSdCkgNRSTtZI3v+uPRU0oA50ePrPRKVH6m+P//Z"
 )
+
+// softwareRulesEnv names a file that replaces the built-in software fingerprinting rules
+const softwareRulesEnv = "TORCH_SOFTWARE_RULES"
+
+func init() {
+	path := os.Getenv(softwareRulesEnv)
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = torch.SetSoftwareRules(data)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("software rules: %s", err))
+	}
+}
+
+// options returns the lookup options shared by all handlers
+func options() torch.Options {
+	return torch.Options{
+		Timeout:            statusTimeout,
+		ConnectTimeout:     statusConnectTimeout,
+		ReadTimeout:        statusReadTimeout,
+		Retries:            statusRetries,
+		RetryBackoff:       statusRetryBackoff,
+		RetransmitInterval: statusRetransmitInterval,
+		PingSamples:        statusPingSamples,
+		Resolver:           resolver,
+		Dialer:             dialer,
+	}
+}
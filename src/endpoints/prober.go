@@ -0,0 +1,330 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"torch/src/cache"
+	"torch/src/pkg/torch"
+	"torch/src/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prober looks up one kind of server status. Every registered prober is served at Route()/:ip, with
+// its results cached for CacheTime() and structs.OfflineServer as the response when the probe fails,
+// which is cached for longer the more probes failed in a row.
+type Prober interface {
+	// Name identifies the prober and its cache table
+	Name() string
+	// Route is the path prefix of the generated route
+	Route() string
+	// DefaultPort is used for addresses without a port
+	DefaultPort() uint16
+	// CacheTime is how long successful results are cached
+	CacheTime() time.Duration
+	// MaxStale is how long expired results are still served while they are refreshed in the background
+	MaxStale() time.Duration
+	// Params are the query parameters read by Probe, results are cached per combination of them
+	Params() []string
+	// Probe looks up the target, returning a *paramError if the query parameters are invalid
+	Probe(ctx context.Context, target torch.Target, query url.Values) (interface{}, error)
+	// Decode decodes a result of Probe from the JSON it was cached as
+	Decode(data []byte) (interface{}, error)
+}
+
+// probers holds the registered probers in registration order
+var probers = make([]Prober, 0)
+
+// Register adds a prober, whose route is served once RegisterRoutes is called
+func Register(prober Prober) {
+	for _, registered := range probers {
+		if registered.Name() == prober.Name() {
+			panic(fmt.Sprintf("prober %q registered twice", prober.Name()))
+		}
+	}
+
+	probers = append(probers, prober)
+}
+
+// RegisterRoutes adds the routes of all registered probers to the router
+func RegisterRoutes(router gin.IRoutes) {
+	for _, prober := range probers {
+		router.GET(prober.Route()+"/:ip", probeHandler(prober))
+	}
+}
+
+// paramError is returned by probes for invalid query parameters and answered with 400
+type paramError struct {
+	message string
+}
+
+func (e *paramError) Error() string {
+	return e.message
+}
+
+func badParam(format string, args ...interface{}) error {
+	return &paramError{fmt.Sprintf(format, args...)}
+}
+
+// probe implements Prober for a lookup returning results of type T
+type probe[T any] struct {
+	name        string
+	route       string
+	defaultPort uint16
+	cacheTime   time.Duration
+	maxStale    time.Duration
+	params      []string
+	run         func(ctx context.Context, target torch.Target, query url.Values) (T, error)
+}
+
+func (p *probe[T]) Name() string             { return p.name }
+func (p *probe[T]) Route() string            { return p.route }
+func (p *probe[T]) DefaultPort() uint16      { return p.defaultPort }
+func (p *probe[T]) CacheTime() time.Duration { return p.cacheTime }
+func (p *probe[T]) MaxStale() time.Duration  { return p.maxStale }
+func (p *probe[T]) Params() []string         { return p.params }
+
+func (p *probe[T]) Probe(ctx context.Context, target torch.Target, query url.Values) (interface{}, error) {
+	return p.run(ctx, target, query)
+}
+
+func (p *probe[T]) Decode(data []byte) (interface{}, error) {
+	var result T
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// cacheEntry is a cached result or offline record, fresh until ExpiresAt and kept for MaxStale()
+// after that
+type cacheEntry struct {
+	Result     json.RawMessage        `json:"result,omitempty"`
+	Offline    *structs.OfflineServer `json:"offline,omitempty"`
+	ObtainedAt time.Time              `json:"obtained_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+	// Failures counts the failed probes since the last successful one
+	Failures   int       `json:"failures"`
+	LastOnline time.Time `json:"last_online"`
+}
+
+// decode returns the result of the entry with its cache state set, or an *offlineError. Every call
+// returns a new copy, so results are never shared between requests.
+func (e *cacheEntry) decode(prober Prober, cacheStatus string) (interface{}, error) {
+	if e.Offline != nil {
+		offline := *e.Offline
+		offline.CacheStatus = cacheStatus
+		return nil, &offlineError{&offline}
+	}
+
+	result, err := prober.Decode(e.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	if setter, ok := result.(interface{ SetCacheStatus(string) }); ok {
+		setter.SetCacheStatus(cacheStatus)
+	}
+	return result, nil
+}
+
+// offlineError is returned for targets that are offline, whether they were just probed or cached
+type offlineError struct {
+	server *structs.OfflineServer
+}
+
+func (e *offlineError) Error() string {
+	return e.server.Error
+}
+
+// offlineCacheTimeFor returns how long an offline record is fresh after failures failed probes in a
+// row, doubling from offlineCacheTime up to offlineMaxCacheTime
+func offlineCacheTimeFor(failures int) time.Duration {
+	cacheTime := offlineCacheTime
+	for i := 1; i < failures && cacheTime < offlineMaxCacheTime; i++ {
+		cacheTime *= 2
+	}
+
+	if cacheTime > offlineMaxCacheTime {
+		return offlineMaxCacheTime
+	}
+	return cacheTime
+}
+
+// probeCall is a probe in flight, shared by all requests for the same target and parameters
+type probeCall struct {
+	done  chan struct{}
+	entry *cacheEntry
+	err   error
+}
+
+var (
+	probeCalls     = make(map[string]*probeCall)
+	probeCallsLock sync.Mutex
+)
+
+// proberCache returns the cache table of the prober
+func proberCache(prober Prober) cache.Cache {
+	return cache.Prefix(cacheStore, prober.Name())
+}
+
+// probeCached returns the result of the prober for the target and parameters with its cache state
+// set, along with the cache entry it was read from which is also returned for offline targets.
+// Concurrent misses wait for the same probe and expired results are returned as stale while they are
+// refreshed in the background.
+func probeCached(ctx context.Context, prober Prober, target torch.Target, query url.Values) (interface{}, *cacheEntry, error) {
+	cacheKey := target.String()
+	for _, param := range prober.Params() {
+		cacheKey += "/" + query.Get(param)
+	}
+
+	// A cache that can't be read is treated like a miss
+	entry := &cacheEntry{}
+	if err := cache.GetJSON(ctx, proberCache(prober), cacheKey, entry); err == nil {
+		cacheStatus := structs.CacheCached
+		if !time.Now().Before(entry.ExpiresAt) {
+			cacheStatus = structs.CacheStale
+			probeShared(prober, cacheKey, target, query)
+		}
+
+		result, err := entry.decode(prober, cacheStatus)
+		return result, entry, err
+	}
+
+	call := probeShared(prober, cacheKey, target, query)
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, nil, call.err
+		}
+		result, err := call.entry.decode(prober, structs.CacheFresh)
+		return result, call.entry, err
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// probeShared starts a probe unless one is already running for the cache key and caches its result.
+// The probe is not bound to any request as others may be waiting for it.
+func probeShared(prober Prober, cacheKey string, target torch.Target, query url.Values) *probeCall {
+	callKey := prober.Name() + "/" + cacheKey
+
+	probeCallsLock.Lock()
+	defer probeCallsLock.Unlock()
+
+	if call, ok := probeCalls[callKey]; ok {
+		return call
+	}
+
+	call := &probeCall{done: make(chan struct{})}
+	probeCalls[callKey] = call
+
+	go func() {
+		ctx := context.Background()
+		result, err := prober.Probe(ctx, target, query)
+		call.entry, call.err = cacheProbe(ctx, prober, cacheKey, target, result, err)
+
+		probeCallsLock.Lock()
+		delete(probeCalls, callKey)
+		probeCallsLock.Unlock()
+
+		close(call.done)
+	}()
+
+	return call
+}
+
+// cacheProbe caches the outcome of a probe as an entry, failures are cached as offline records except
+// for invalid parameters. Errors of the cache are ignored so results are served without it.
+func cacheProbe(ctx context.Context, prober Prober, cacheKey string, target torch.Target, result interface{}, err error) (*cacheEntry, error) {
+	table := proberCache(prober)
+	now := time.Now()
+
+	var paramErr *paramError
+	if errors.As(err, &paramErr) {
+		return nil, err
+	}
+
+	if err == nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := &cacheEntry{
+			Result:     data,
+			ObtainedAt: now,
+			ExpiresAt:  now.Add(prober.CacheTime()),
+			LastOnline: now,
+		}
+		cache.SetJSON(ctx, table, cacheKey, entry, prober.CacheTime()+prober.MaxStale())
+		return entry, nil
+	}
+
+	entry := &cacheEntry{ObtainedAt: now, Failures: 1}
+	previous := &cacheEntry{}
+	if cache.GetJSON(ctx, table, cacheKey, previous) == nil {
+		entry.Failures = previous.Failures + 1
+		entry.LastOnline = previous.LastOnline
+	}
+
+	cacheTime := offlineCacheTimeFor(entry.Failures)
+	entry.ExpiresAt = now.Add(cacheTime)
+	entry.Offline = &structs.OfflineServer{
+		Offline:   true,
+		Host:      target.Host,
+		Port:      target.Port,
+		Error:     err.Error(),
+		Failures:  entry.Failures,
+		ExpiresAt: entry.ExpiresAt,
+	}
+	if !entry.LastOnline.IsZero() {
+		lastOnline := entry.LastOnline
+		entry.Offline.LastOnline = &lastOnline
+	}
+
+	cache.SetJSON(ctx, table, cacheKey, entry, cacheTime+prober.MaxStale())
+	return entry, nil
+}
+
+func probeHandler(prober Prober) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		target, ok := targetParam(c, "ip", prober.DefaultPort())
+		if !ok {
+			return
+		}
+
+		result, entry, err := probeCached(c.Request.Context(), prober, target, c.Request.URL.Query())
+
+		var paramErr *paramError
+		if errors.As(err, &paramErr) {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		var offlineErr *offlineError
+		if errors.As(err, &offlineErr) {
+			respondCached(c, offlineErr.server, entry.ObtainedAt, entry.ExpiresAt)
+			return
+		}
+
+		// The request was cancelled before the probe finished
+		if err != nil {
+			c.JSON(200, structs.OfflineServer{
+				Offline: true,
+				Host:    target.Host,
+				Port:    target.Port,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		respondCached(c, result, entry.ObtainedAt, entry.ExpiresAt)
+	}
+}
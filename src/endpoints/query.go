@@ -0,0 +1,32 @@
+package endpoints
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"torch/src/pkg/torch"
+	"torch/src/structs"
+)
+
+func init() {
+	Register(&probe[*structs.QueryStatus]{
+		name:        "query",
+		route:       "/query",
+		defaultPort: 25565,
+		cacheTime:   statusCacheTime,
+		maxStale:    statusMaxStale,
+		run:         fetchQuery,
+	})
+}
+
+func fetchQuery(ctx context.Context, target torch.Target, query url.Values) (*structs.QueryStatus, error) {
+	status, err := torch.FetchQuery(ctx, target.Host, target.Port, options())
+	if err != nil {
+		return nil, err
+	}
+
+	status.ExpiresAt = time.Now().Add(statusCacheTime)
+
+	return status, nil
+}
@@ -1,51 +1,41 @@
 package endpoints
 
 import (
-	"net"
 	"time"
+
+	"torch/src/cache"
+	"torch/src/pkg/torch"
 	"torch/src/structs"
 
 	"github.com/gin-gonic/gin"
 )
 
-func srv(host string) (*structs.Srv, error) {
-	_, addrs, err := net.LookupSRV("minecraft", "tcp", host)
-
+func SrvHandler(c *gin.Context) {
+	host, err := torch.ParseHost(c.Param("host"))
 	if err != nil {
-		return nil, err
-	}
-
-	if len(addrs) < 1 {
-		return nil, nil
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
 
-	return &structs.Srv{
-		Target:     addrs[0].Target,
-		Port:       addrs[0].Port,
-		ObtainedAt: time.Now(),
-		ExpiresAt:  time.Now().Add(time.Duration(srvCacheTime)),
-	}, nil
-}
-
-func SrvHandler(c *gin.Context) {
-	host := c.Param("host")
+	ctx := c.Request.Context()
 
-	data, err := srvCache.Value(host)
-	if err == nil {
-		c.JSON(200, data.Data().(*structs.Srv))
+	cached := &structs.Srv{}
+	if err := cache.GetJSON(ctx, srvCache, host, cached); err == nil {
+		respondCached(c, cached, cached.ObtainedAt, cached.ExpiresAt)
 		return
 	}
 
-	srv, err := srv(host)
+	srv, err := torch.LookupSRV(ctx, host, options())
 	if err != nil || srv == nil {
 		srv = &structs.Srv{
 			Target:     host,
 			Port:       25565,
+			Records:    make([]structs.SrvTarget, 0),
 			ObtainedAt: time.Now(),
-			ExpiresAt:  time.Now().Add(time.Duration(srvCacheTime)),
+			ExpiresAt:  time.Now().Add(srvMissingCacheTime),
 		}
 	}
 
-	srvCache.Add(host, srvCacheTime, srv)
-	c.JSON(200, srv)
+	cache.SetJSON(ctx, srvCache, host, srv, time.Until(srv.ExpiresAt))
+	respondCached(c, srv, srv.ObtainedAt, srv.ExpiresAt)
 }
@@ -0,0 +1,85 @@
+package endpoints
+
+import (
+	"sync"
+	"time"
+
+	"torch/src/pkg/torch"
+	"torch/src/structs"
+
+	"github.com/gin-gonic/gin"
+)
+
+func StatusHandler(c *gin.Context) {
+	target, ok := targetParam(c, "address", 0)
+	if !ok {
+		return
+	}
+
+	// An explicit port is probed on both editions
+	javaTarget, bedrockTarget := target, target
+	if target.Port == 0 {
+		javaTarget.Port, bedrockTarget.Port = 25565, 19132
+	}
+
+	result := structs.Status{
+		Host: target.Host,
+	}
+
+	ctx := c.Request.Context()
+
+	// Entries of both editions, including offline ones, so the response expires with the first of them
+	var javaEntry, bedrockEntry *cacheEntry
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		java, entry, err := probeCached(ctx, javaProber, javaTarget, nil)
+		javaEntry = entry
+		if err == nil {
+			result.Java = java.(*structs.JavaStatus)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		bedrock, entry, err := probeCached(ctx, bedrockProber, bedrockTarget, nil)
+		bedrockEntry = entry
+		if err == nil {
+			result.Bedrock = bedrock.(*structs.BedrockStatus)
+		}
+	}()
+
+	wg.Wait()
+
+	switch {
+	case result.Java != nil && result.Bedrock != nil:
+		result.Edition = structs.EditionBoth
+		result.Crossplay = torch.CorrelateCrossplay(result.Java, result.Bedrock)
+	case result.Java != nil:
+		result.Edition = structs.EditionJava
+	case result.Bedrock != nil:
+		result.Edition = structs.EditionBedrock
+	default:
+		result.Edition = structs.EditionNone
+	}
+
+	var obtainedAt, expiresAt time.Time
+	for _, entry := range []*cacheEntry{javaEntry, bedrockEntry} {
+		// A request cancelled before its probe finished has no entry and is not cached
+		if entry == nil {
+			obtainedAt, expiresAt = time.Time{}, time.Time{}
+			break
+		}
+		if entry.ObtainedAt.After(obtainedAt) {
+			obtainedAt = entry.ObtainedAt
+		}
+		if expiresAt.IsZero() || entry.ExpiresAt.Before(expiresAt) {
+			expiresAt = entry.ExpiresAt
+		}
+	}
+
+	respondCached(c, &result, obtainedAt, expiresAt)
+}
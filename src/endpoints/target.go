@@ -0,0 +1,18 @@
+package endpoints
+
+import (
+	"torch/src/pkg/torch"
+
+	"github.com/gin-gonic/gin"
+)
+
+// targetParam parses the named route parameter, responding with 400 if it is invalid
+func targetParam(c *gin.Context, name string, defaultPort uint16) (torch.Target, bool) {
+	t, err := torch.ParseTarget(c.Param(name), defaultPort)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return t, false
+	}
+
+	return t, true
+}
@@ -0,0 +1,31 @@
+package endpoints
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestInvalidTargetsAreRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/status/java/:ip", probeHandler(javaProber))
+	router.GET("/dns/:host", DnsHandler)
+
+	for _, path := range []string{
+		"/status/java/" + url.PathEscape("example.com:65536"),
+		"/status/java/" + url.PathEscape("example.com:+80"),
+		"/status/java/" + url.PathEscape("a..b"),
+		"/status/java/..",
+		"/dns/.",
+		"/dns/" + url.PathEscape("exa mple.com"),
+	} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		if recorder.Code != 400 {
+			t.Errorf("GET %s = %d %s, want 400", path, recorder.Code, recorder.Body)
+		}
+	}
+}
@@ -13,9 +13,10 @@ func main() {
 
 	router.Use(cors.Default())
 
-	router.GET("/status/java/:ip", endpoints.FetchJavaHandler)
-	router.GET("/status/bedrock/:ip", endpoints.FetchBedrockHandler)
+	endpoints.RegisterRoutes(router)
+	router.GET("/status/:address", endpoints.StatusHandler)
 	router.GET("/srv/:host", endpoints.SrvHandler)
+	router.GET("/dns/:host", endpoints.DnsHandler)
 	router.GET("/icon/:ip", endpoints.IconHandler)
 	router.GET("/ping", endpoints.PingHandler)
 
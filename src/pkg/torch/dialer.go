@@ -0,0 +1,33 @@
+package torch
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// directDialer connects to servers directly, optionally from a fixed source address
+type directDialer struct {
+	source net.IP
+}
+
+// NewDialer returns a Dialer that connects directly, binding outgoing TCP and UDP connections to
+// source unless it is nil
+func NewDialer(source net.IP) Dialer {
+	return &directDialer{source}
+}
+
+func (d *directDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	dialer := net.Dialer{}
+
+	if d.source != nil {
+		switch {
+		case strings.HasPrefix(network, "tcp"):
+			dialer.LocalAddr = &net.TCPAddr{IP: d.source}
+		case strings.HasPrefix(network, "udp"):
+			dialer.LocalAddr = &net.UDPAddr{IP: d.source}
+		}
+	}
+
+	return dialer.DialContext(ctx, network, address)
+}
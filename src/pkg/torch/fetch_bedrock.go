@@ -0,0 +1,299 @@
+package torch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"torch/src/structs"
+)
+
+// maxDatagramSize is the largest UDP payload that can be received
+const maxDatagramSize = 65535
+
+var bedrockMagic = []byte{0x00, 0xFF, 0xFF, 0x00, 0xFE, 0xFE, 0xFE, 0xFE, 0xFD, 0xFD, 0xFD, 0xFD, 0x12, 0x34, 0x56, 0x78}
+
+// FetchBedrock sends unconnected pings to a Bedrock server, resending them every
+// opts.RetransmitInterval until a pong arrives and retrying up to opts.Retries times
+func FetchBedrock(ctx context.Context, host string, port uint16, opts Options) (*structs.BedrockStatus, error) {
+	status, attempts, err := retry(ctx, opts, isRetryable, func() (*structs.BedrockStatus, error) {
+		return fetchBedrock(ctx, host, port, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status.Attempts = attempts
+	return status, nil
+}
+
+func fetchBedrock(ctx context.Context, host string, port uint16, opts Options) (*structs.BedrockStatus, error) {
+	// Sending UDP never fails, so each address is raced with a full ping instead of just a dial
+	race, err := raceTarget(ctx, opts, "udp", Target{host, port}, func(ctx context.Context, address string) (*structs.BedrockStatus, error) {
+		return pingBedrock(ctx, host, port, address, opts)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	status := race.value
+	status.IP = race.ip
+	status.Addresses = race.addresses
+	status.Timings.DNSMs = structs.Milliseconds(race.dns)
+
+	return status, nil
+}
+
+// pingBedrock keeps several pings in flight, as any of them may be lost, and measures each round trip
+// from the ping whose time is echoed in the pong. After the first pong further pings are sent one at
+// a time until opts.PingSamples round trips were measured or no pong arrives before the read timeout.
+func pingBedrock(ctx context.Context, host string, port uint16, address string, opts Options) (*structs.BedrockStatus, error) {
+	connectStart := time.Now()
+	conn, err := opts.dial(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer closeOnCancel(ctx, conn)()
+	connectTime := time.Since(connectStart)
+
+	// Send times of the pings waiting for a pong by the time in them
+	sent := make(map[int64]time.Time)
+	var lastPingTime int64
+	var pings int
+	var retransmitAt, statusReceived time.Time
+	var serverGUID int64
+	var serverName []byte
+
+	statusStart := time.Now()
+	deadline := opts.deadline(ctx)
+	samples := make([]time.Duration, 0, opts.pingSamples())
+
+	datagram := make([]byte, maxDatagramSize)
+	for len(samples) < opts.pingSamples() {
+		if now := time.Now(); !now.Before(retransmitAt) {
+			// Pings sent within the same millisecond still need distinct times to be told apart
+			pingTime := now.UnixMilli()
+			if pingTime <= lastPingTime {
+				pingTime = lastPingTime + 1
+			}
+			lastPingTime = pingTime
+
+			if err = conn.SetWriteDeadline(deadline); err != nil {
+				return nil, err
+			}
+			if err := sendBedrockPing(conn, pingTime); err != nil {
+				return nil, err
+			}
+			sent[pingTime] = now
+			pings++
+			retransmitAt = now.Add(opts.retransmitInterval())
+		}
+
+		readDeadline := retransmitAt
+		if deadline.Before(readDeadline) {
+			readDeadline = deadline
+		}
+		if err = conn.SetReadDeadline(readDeadline); err != nil {
+			return nil, err
+		}
+
+		// Unconnected Pong, read as a single datagram so no length in it can exceed what was received
+		n, err := conn.Read(datagram)
+		received := time.Now()
+		if errors.Is(err, os.ErrDeadlineExceeded) && received.Before(deadline) {
+			continue
+		}
+
+		var returnedPingTime, guid int64
+		var name []byte
+		if err == nil {
+			returnedPingTime, guid, name, err = parseBedrockPong(datagram[:n])
+		}
+		if err != nil {
+			// Missing samples don't fail a ping that already has a status
+			if len(samples) > 0 && ctx.Err() == nil {
+				break
+			}
+			return nil, err
+		}
+
+		// Pongs from another server than the first pong came from, e.g. a spoofed one, are ignored
+		if len(samples) > 0 && guid != serverGUID {
+			continue
+		}
+
+		// Pongs to pings that were not sent on this connection, or were already answered, are ignored
+		sentAt, ok := sent[returnedPingTime]
+		if !ok {
+			continue
+		}
+		delete(sent, returnedPingTime)
+		samples = append(samples, received.Sub(sentAt))
+
+		if len(samples) == 1 {
+			serverGUID, serverName = guid, name
+			statusReceived = received
+			deadline = opts.deadline(ctx)
+		}
+
+		// The next sample is sent right away
+		retransmitAt = received
+	}
+
+	split := strings.Split(string(serverName), ";")
+
+	var status structs.BedrockStatus
+	var _value int64
+	var _motd string
+
+	for key, value := range split {
+		if len(strings.Trim(value, " ")) < 1 {
+			continue
+		}
+
+		switch key {
+		case 0:
+			status.Edition = value
+		case 1:
+			_motd = value
+		case 2:
+			_value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			status.Version.Protocol = int(_value)
+		case 3:
+			status.Version.Name = structs.Parse(value)
+		case 4:
+			_value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			status.Players.Online = int(_value)
+		case 5:
+			_value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			status.Players.Max = int(_value)
+		case 6:
+			status.ServerID = value
+		case 7:
+			_motd += "\n&r" + value
+		case 8:
+			status.Gamemode = value
+		case 9:
+			_value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			status.GamemodeId = int(_value)
+		case 10:
+			_value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			intValue := int(_value)
+			status.PortIPv4 = &intValue
+		case 11:
+			_value, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			intValue := int(_value)
+			status.PortIPv6 = &intValue
+		}
+	}
+
+	if len(_motd) > 0 {
+		status.MOTD = structs.Parse(_motd)
+	}
+	status.ServerGUID = serverGUID
+	status.Host = host
+	status.Port = port
+	status.ObtainedAt = time.Now()
+	status.Latency = structs.NewLatency(samples)
+	status.Pings = pings
+	status.Timings = structs.Timings{
+		ConnectMs: structs.Milliseconds(connectTime),
+		StatusMs:  structs.Milliseconds(statusReceived.Sub(statusStart)),
+		PingMs:    structs.Milliseconds(time.Since(statusReceived)),
+	}
+	status.Software = fingerprintBedrock(&status, split)
+
+	return &status, nil
+}
+
+func sendBedrockPing(conn net.Conn, pingTime int64) error {
+	// Unconnected Ping
+	buf := &bytes.Buffer{}
+	// Packet ID
+	if err := buf.WriteByte(0x01); err != nil {
+		return err
+	}
+	// Time
+	if err := binary.Write(buf, binary.BigEndian, pingTime); err != nil {
+		return err
+	}
+	// Magic
+	if _, err := buf.Write(bedrockMagic); err != nil {
+		return err
+	}
+	// Client GUID
+	if err := binary.Write(buf, binary.BigEndian, uint64(0)); err != nil {
+		return err
+	}
+
+	_, err := conn.Write(buf.Bytes())
+	return err
+}
+
+// parseBedrockPong returns the echoed ping time, the server GUID and the server name of an Unconnected Pong
+func parseBedrockPong(datagram []byte) (int64, int64, []byte, error) {
+	reader := bytes.NewReader(datagram)
+
+	var packetId byte
+	var returnedPingTime, serverGUID int64
+	var serverNameLength uint16
+
+	if err := binary.Read(reader, binary.BigEndian, &packetId); err != nil {
+		return 0, 0, nil, err
+	}
+	if packetId != 0x1C {
+		return 0, 0, nil, fmt.Errorf("unexpected packet ID (expected 0x1c, got 0x%02x)", packetId)
+	}
+	if err := binary.Read(reader, binary.BigEndian, &returnedPingTime); err != nil {
+		return 0, 0, nil, err
+	}
+	if err := binary.Read(reader, binary.BigEndian, &serverGUID); err != nil {
+		return 0, 0, nil, err
+	}
+	magic := make([]byte, len(bedrockMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return 0, 0, nil, err
+	}
+	if !bytes.Equal(magic, bedrockMagic) {
+		return 0, 0, nil, fmt.Errorf("unexpected magic (got %x)", magic)
+	}
+	if err := binary.Read(reader, binary.BigEndian, &serverNameLength); err != nil {
+		return 0, 0, nil, err
+	}
+	if int(serverNameLength) > reader.Len() {
+		return 0, 0, nil, fmt.Errorf("server name length %d exceeds remaining %d bytes", serverNameLength, reader.Len())
+	}
+	serverName := make([]byte, serverNameLength)
+	if _, err := io.ReadFull(reader, serverName); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return returnedPingTime, serverGUID, serverName, nil
+}
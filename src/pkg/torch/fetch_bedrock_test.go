@@ -0,0 +1,103 @@
+package torch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// bedrockPong encodes an Unconnected Pong, nameLength is written as is so it may disagree with name
+func bedrockPong(pingTime int64, guid int64, magic []byte, nameLength uint16, name string) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(0x1C)
+	binary.Write(buf, binary.BigEndian, pingTime)
+	binary.Write(buf, binary.BigEndian, guid)
+	buf.Write(magic)
+	binary.Write(buf, binary.BigEndian, nameLength)
+	buf.WriteString(name)
+	return buf.Bytes()
+}
+
+func FuzzParseBedrockPong(f *testing.F) {
+	name := "MCPE;Dedicated Server;589;1.20.0;0;10;13253860892328930865;Bedrock level;Survival;1;19132;19133;"
+	pong := bedrockPong(1234, 5678, bedrockMagic, uint16(len(name)), name)
+
+	f.Add(pong)
+	f.Add([]byte{})
+	// Wrong packet ID
+	f.Add(append([]byte{0x1D}, pong[1:]...))
+	// Truncated within the header, the magic and the name
+	f.Add(pong[:10])
+	f.Add(pong[:20])
+	f.Add(pong[:len(pong)-10])
+	// Bad magic
+	f.Add(bedrockPong(1234, 5678, make([]byte, len(bedrockMagic)), uint16(len(name)), name))
+	// Name length larger than the datagram
+	f.Add(bedrockPong(1234, 5678, bedrockMagic, 0xFFFF, name))
+
+	f.Fuzz(func(t *testing.T, datagram []byte) {
+		pingTime, guid, serverName, err := parseBedrockPong(datagram)
+		if err != nil {
+			return
+		}
+
+		// A parsed pong encodes back to the start of the datagram
+		encoded := bedrockPong(pingTime, guid, bedrockMagic, uint16(len(serverName)), string(serverName))
+		if !bytes.HasPrefix(datagram, encoded) {
+			t.Fatalf("parsed %d, %d, %q from %x", pingTime, guid, serverName, datagram)
+		}
+	})
+}
+
+// TestPingBedrockIgnoresOtherGUIDs answers the first ping with one GUID and every later ping with
+// another, whose pongs must not be counted as samples
+func TestPingBedrockIgnoresOtherGUIDs(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	const guid, otherGUID = 1111, 2222
+	name := "MCPE;Dedicated Server;589;1.20.0;0;10;1111;Bedrock level;Survival;1;19132;19133;"
+
+	go func() {
+		buf := make([]byte, 1500)
+		for pongs := 0; ; pongs++ {
+			n, addr, err := server.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if n < 9 || buf[0] != 0x01 {
+				continue
+			}
+
+			pingTime := int64(binary.BigEndian.Uint64(buf[1:9]))
+			responder := int64(guid)
+			if pongs > 0 {
+				responder = otherGUID
+			}
+			server.WriteTo(bedrockPong(pingTime, responder, bedrockMagic, uint16(len(name)), name), addr)
+		}
+	}()
+
+	opts := Options{
+		Timeout:            300 * time.Millisecond,
+		RetransmitInterval: 50 * time.Millisecond,
+		PingSamples:        3,
+	}
+	status, err := pingBedrock(context.Background(), "127.0.0.1", 19132, server.LocalAddr().String(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.ServerGUID != guid {
+		t.Errorf("server GUID = %d, want %d", status.ServerGUID, guid)
+	}
+	if status.Latency.Samples != 1 {
+		t.Errorf("%d samples, want only the one of the first pong", status.Latency.Samples)
+	}
+}
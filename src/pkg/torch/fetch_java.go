@@ -0,0 +1,314 @@
+package torch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"torch/src/protocol"
+	"torch/src/structs"
+)
+
+// FetchJava pings a Java server, following its SRV record according to opts.SrvMode and falling back
+// to the legacy pings of servers older than 1.7. The DNS, dial and read phases are aborted once ctx
+// is done. Failed pings are retried up to opts.Retries times, unless the server accepted the connection
+// but didn't answer within the read timeout.
+func FetchJava(ctx context.Context, host string, port uint16, opts Options) (*structs.JavaStatus, error) {
+	status, attempts, err := retry(ctx, opts, isJavaRetryable, func() (*structs.JavaStatus, error) {
+		return fetchJava(ctx, host, port, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status.Attempts = attempts
+	return status, nil
+}
+
+func fetchJava(ctx context.Context, host string, port uint16, opts Options) (*structs.JavaStatus, error) {
+	candidates, srv := javaCandidates(ctx, host, port, opts)
+
+	var err error
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var status *structs.JavaStatus
+		status, err = fetchJavaTarget(ctx, host, port, candidate, opts)
+		if err == nil {
+			if srv != nil {
+				status.SrvRecord = &structs.SrvRecord{
+					Host:    candidate.Host,
+					Port:    candidate.Port,
+					Records: srv.Records,
+				}
+			}
+			return status, nil
+		}
+
+		// Only unreachable targets fail over to the next SRV record
+		if !isDialError(err) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func fetchJavaTarget(ctx context.Context, originalHost string, originalPort uint16, target Target, opts Options) (*structs.JavaStatus, error) {
+	status, err := fetchJavaNetty(ctx, originalHost, originalPort, target.Host, target.Port, opts)
+	if err == nil {
+		return status, nil
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Servers that are unreachable or silent will not answer a legacy ping either
+	if isDialError(err) || !isLegacyRejection(err) {
+		return nil, err
+	}
+
+	for _, variant := range legacyPingVariants {
+		status, legacyErr := fetchJavaLegacy(ctx, originalHost, originalPort, target.Host, target.Port, variant, opts)
+		if legacyErr == nil {
+			return status, nil
+		}
+	}
+
+	return nil, err
+}
+
+func fetchJavaNetty(ctx context.Context, originalHost string, originalPort uint16, host string, port uint16, opts Options) (*structs.JavaStatus, error) {
+	race, err := dialTarget(ctx, opts, "tcp", Target{host, port})
+	if err != nil {
+		return nil, err
+	}
+	conn := race.value
+	defer conn.Close()
+	defer closeOnCancel(ctx, conn)()
+
+	if err = conn.SetDeadline(opts.deadline(ctx)); err != nil {
+		return nil, err
+	}
+
+	packetConn := protocol.NewConn(conn, opts.protocolVersion())
+	defer packetConn.Release()
+
+	statusStart := time.Now()
+
+	if err = sendHandshake(packetConn, host+forgeMarkers[opts.ForgeMarker], port, protocol.StateStatus); err != nil {
+		return nil, err
+	}
+
+	if err = packetConn.WritePacket(&protocol.StatusRequest{}); err != nil {
+		return nil, err
+	}
+
+	start, err := packetConn.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if isLegacyKick(start) {
+		return nil, errLegacyKick
+	}
+
+	rawJavaResponse, err := readStatusResponse(packetConn)
+	if err != nil {
+		return nil, err
+	}
+	statusTime := time.Since(statusStart)
+
+	pingStart := time.Now()
+	samples, err := pingJava(ctx, conn, packetConn, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	status := createJavaStatus(originalHost, originalPort, rawJavaResponse, structs.PingVersionNetty, opts.protocolVersion())
+	status.IP = remoteIP(conn)
+	status.Addresses = race.addresses
+	status.Latency = structs.NewLatency(samples)
+	status.Timings = structs.Timings{
+		DNSMs:     structs.Milliseconds(race.dns),
+		ConnectMs: structs.Milliseconds(race.attempt),
+		StatusMs:  structs.Milliseconds(statusTime),
+		PingMs:    structs.Milliseconds(time.Since(pingStart)),
+	}
+
+	return status, nil
+}
+
+// pingJava measures up to opts.PingSamples round trips, failing only if there is none as servers
+// may close the connection after the first pong
+func pingJava(ctx context.Context, conn net.Conn, packetConn *protocol.Conn, opts Options) ([]time.Duration, error) {
+	samples := make([]time.Duration, 0, opts.pingSamples())
+
+	for len(samples) < opts.pingSamples() {
+		if err := conn.SetDeadline(opts.deadline(ctx)); err != nil {
+			return samples, err
+		}
+
+		pingStart := time.Now()
+		pingPayload := pingStart.UnixNano()
+
+		err := packetConn.WritePacket(&protocol.PingRequest{Payload: pingPayload})
+		if err == nil {
+			err = readPong(packetConn, pingPayload)
+		}
+		if err != nil {
+			if len(samples) > 0 && ctx.Err() == nil {
+				break
+			}
+			return nil, err
+		}
+
+		samples = append(samples, time.Since(pingStart))
+	}
+
+	return samples, nil
+}
+
+func sendHandshake(conn *protocol.Conn, host string, port uint16, nextState protocol.State) error {
+	if err := conn.WritePacket(&protocol.Handshake{
+		ProtocolVersion: conn.Protocol,
+		ServerAddress:   host,
+		ServerPort:      port,
+		NextState:       nextState,
+	}); err != nil {
+		return err
+	}
+
+	conn.State = nextState
+	return nil
+}
+
+func readStatusResponse(conn *protocol.Conn) (structs.RawJavaStatus, error) {
+	var rawJavaResponse structs.RawJavaStatus
+
+	packet, err := conn.ReadPacket()
+	if err != nil {
+		return rawJavaResponse, err
+	}
+
+	response, ok := packet.(*protocol.StatusResponse)
+	if !ok {
+		return rawJavaResponse, fmt.Errorf("unexpected packet ID (expected 0x00, got 0x%02x)", packet.ID())
+	}
+
+	err = json.Unmarshal(response.JSON, &rawJavaResponse)
+	return rawJavaResponse, err
+}
+
+func readPong(conn *protocol.Conn, pingPayload int64) error {
+	packet, err := conn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	pong, ok := packet.(*protocol.PongResponse)
+	if !ok {
+		return fmt.Errorf("unexpected packet ID (expected 0x01, got 0x%02x)", packet.ID())
+	}
+
+	if pong.Payload != pingPayload {
+		return fmt.Errorf("unexpected payload (expected %d, got %d)", pingPayload, pong.Payload)
+	}
+
+	return nil
+}
+
+func createJavaStatus(originalHost string, originalPort uint16, rawJavaResponse structs.RawJavaStatus, pingVersion string, protocolVersion int32) *structs.JavaStatus {
+	// Process data
+	description := structs.Parse(rawJavaResponse.Description)
+
+	samplePlayers := make([]structs.Player, 0)
+
+	if rawJavaResponse.Players.Sample != nil {
+		for _, player := range rawJavaResponse.Players.Sample {
+			name := structs.Parse(player.Name)
+			samplePlayers = append(samplePlayers, structs.Player{
+				ID:   player.ID,
+				Name: *name,
+			})
+		}
+	}
+
+	versionText := structs.Parse(rawJavaResponse.Version.Name)
+
+	result := &structs.JavaStatus{
+		Host: originalHost,
+		Port: originalPort,
+		Version: structs.Version{
+			Name:     versionText,
+			Protocol: rawJavaResponse.Version.Protocol,
+		},
+		Players: structs.Players{
+			Max:    rawJavaResponse.Players.Max,
+			Online: rawJavaResponse.Players.Online,
+			Sample: samplePlayers,
+		},
+		Description: description,
+		Icon:        rawJavaResponse.Favicon,
+		PingVersion: pingVersion,
+		ModInfo:     nil,
+		ObtainedAt:  time.Now(),
+	}
+
+	if len(rawJavaResponse.ModInfo.Type) > 0 {
+		mods := make([]structs.Mod, 0)
+		for _, mod := range rawJavaResponse.ModInfo.List {
+			mods = append(mods, structs.Mod{
+				ID:      mod.ModID,
+				Version: mod.Version,
+			})
+		}
+		result.ModInfo = &structs.ModInfo{
+			Type:     rawJavaResponse.ModInfo.Type,
+			ModList:  mods,
+			Channels: make([]structs.Channel, 0),
+		}
+	}
+
+	if rawJavaResponse.ForgeData.Mods != nil || rawJavaResponse.ForgeData.D != "" {
+		mods := make([]structs.Mod, 0)
+		for _, mod := range rawJavaResponse.ForgeData.Mods {
+			mods = append(mods, structs.Mod{
+				ID:      mod.ModID,
+				Version: mod.Version,
+			})
+		}
+		channels := make([]structs.Channel, 0)
+		for _, channel := range rawJavaResponse.ForgeData.Channels {
+			channels = append(channels, structs.Channel{
+				Name:     channel.Res,
+				Version:  channel.Version,
+				Required: channel.Required,
+			})
+		}
+		truncated := rawJavaResponse.ForgeData.Truncated
+
+		// Forge 1.18+ leaves mods and channels empty and packs them into "d" instead
+		if rawJavaResponse.ForgeData.D != "" {
+			if decoded, err := decodeForgeData(rawJavaResponse.ForgeData.D); err == nil {
+				mods, channels, truncated = decoded.Mods, decoded.Channels, decoded.Truncated
+			}
+		}
+
+		result.ModInfo = &structs.ModInfo{
+			Type:              "forge",
+			ModList:           mods,
+			Channels:          channels,
+			FMLNetworkVersion: rawJavaResponse.ForgeData.FMLNetworkVersion,
+			Truncated:         truncated,
+		}
+	}
+
+	result.Software = fingerprintJava(result, protocolVersion)
+
+	return result
+}
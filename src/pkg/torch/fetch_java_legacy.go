@@ -0,0 +1,225 @@
+package torch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf16"
+
+	"torch/src/structs"
+)
+
+// legacyPingVariants are tried in order when a server does not understand the netty handshake
+var legacyPingVariants = []string{structs.PingVersionLegacy, structs.PingVersionBeta}
+
+// legacyProtocolVersion is the protocol advertised in the MC|PingHost plugin message (1.6.4)
+const legacyProtocolVersion = 78
+
+// errLegacyKick is returned by the netty ping when the server answers with a legacy kick packet
+var errLegacyKick = errors.New("server answered the handshake with a legacy kick")
+
+// isLegacyKick reports whether the start of a response is a legacy kick packet, which is 0xFF followed
+// by the high byte of its length. As a netty VarInt length 0xFF 0x00 is not canonical, so no netty
+// server sends it.
+func isLegacyKick(start []byte) bool {
+	return len(start) == 2 && start[0] == 0xFF && start[1] == 0x00
+}
+
+// isLegacyRejection reports whether err is how servers older than 1.7 reject the netty handshake,
+// which they do right away by kicking, closing or resetting the connection. Servers that stay silent
+// until the read timeout are not pinged again.
+func isLegacyRejection(err error) bool {
+	return errors.Is(err, errLegacyKick) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+func fetchJavaLegacy(ctx context.Context, originalHost string, originalPort uint16, host string, port uint16, variant string, opts Options) (*structs.JavaStatus, error) {
+	race, err := dialTarget(ctx, opts, "tcp", Target{host, port})
+	if err != nil {
+		return nil, err
+	}
+	conn := race.value
+	defer conn.Close()
+	defer closeOnCancel(ctx, conn)()
+
+	if err = conn.SetDeadline(opts.deadline(ctx)); err != nil {
+		return nil, err
+	}
+
+	pingStart := time.Now()
+
+	if err = sendLegacyPing(conn, host, port, variant); err != nil {
+		return nil, err
+	}
+
+	kick, err := readLegacyKick(bufio.NewReader(conn))
+	if err != nil {
+		return nil, err
+	}
+
+	rawJavaResponse, err := parseLegacyKick(kick)
+	if err != nil {
+		return nil, err
+	}
+
+	// The kick is the only round trip of a legacy ping, so it is the single latency sample
+	statusTime := time.Since(pingStart)
+
+	status := createJavaStatus(originalHost, originalPort, rawJavaResponse, variant, opts.protocolVersion())
+	status.IP = remoteIP(conn)
+	status.Addresses = race.addresses
+	status.Latency = structs.NewLatency([]time.Duration{statusTime})
+	status.Timings = structs.Timings{
+		DNSMs:     structs.Milliseconds(race.dns),
+		ConnectMs: structs.Milliseconds(race.attempt),
+		StatusMs:  structs.Milliseconds(statusTime),
+	}
+
+	return status, nil
+}
+
+func sendLegacyPing(conn net.Conn, host string, port uint16, variant string) error {
+	buf := &bytes.Buffer{}
+
+	// Server List Ping
+	if err := buf.WriteByte(0xFE); err != nil {
+		return err
+	}
+
+	if variant == structs.PingVersionBeta {
+		_, err := io.Copy(conn, buf)
+		return err
+	}
+
+	// Payload, always 1
+	if err := buf.WriteByte(0x01); err != nil {
+		return err
+	}
+
+	// Plugin Message
+	if err := buf.WriteByte(0xFA); err != nil {
+		return err
+	}
+
+	if err := writeLegacyString("MC|PingHost", buf); err != nil {
+		return err
+	}
+
+	data := &bytes.Buffer{}
+
+	if err := data.WriteByte(legacyProtocolVersion); err != nil {
+		return err
+	}
+
+	if err := writeLegacyString(host, data); err != nil {
+		return err
+	}
+
+	if err := binary.Write(data, binary.BigEndian, int32(port)); err != nil {
+		return err
+	}
+
+	if err := binary.Write(buf, binary.BigEndian, uint16(data.Len())); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(buf, data); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(conn, buf)
+	return err
+}
+
+func readLegacyKick(reader io.Reader) (string, error) {
+	var packetId byte
+	if err := binary.Read(reader, binary.BigEndian, &packetId); err != nil {
+		return "", err
+	}
+
+	if packetId != 0xFF {
+		return "", fmt.Errorf("unexpected packet ID (expected 0xff, got 0x%02x)", packetId)
+	}
+
+	return readLegacyString(reader)
+}
+
+// parseLegacyKick maps the kick message sent in response to a legacy ping onto the netty status format
+func parseLegacyKick(kick string) (structs.RawJavaStatus, error) {
+	var rawJavaResponse structs.RawJavaStatus
+	var err error
+
+	// 1.4 - 1.6: §1\0protocol\0version\0motd\0online\0max
+	if strings.HasPrefix(kick, "§1\x00") {
+		split := strings.Split(kick, "\x00")
+		if len(split) < 6 {
+			return rawJavaResponse, fmt.Errorf("malformed legacy ping response (expected 6 fields, got %d)", len(split))
+		}
+
+		if rawJavaResponse.Version.Protocol, err = strconv.Atoi(split[1]); err != nil {
+			return rawJavaResponse, err
+		}
+		rawJavaResponse.Version.Name = split[2]
+		rawJavaResponse.Description = split[3]
+		if rawJavaResponse.Players.Online, err = strconv.Atoi(split[4]); err != nil {
+			return rawJavaResponse, err
+		}
+		if rawJavaResponse.Players.Max, err = strconv.Atoi(split[5]); err != nil {
+			return rawJavaResponse, err
+		}
+
+		return rawJavaResponse, nil
+	}
+
+	// Beta 1.8 - 1.3: motd§online§max, the MOTD itself may contain §
+	split := strings.Split(kick, "§")
+	if len(split) < 3 {
+		return rawJavaResponse, fmt.Errorf("malformed legacy ping response (expected 3 fields, got %d)", len(split))
+	}
+
+	rawJavaResponse.Description = strings.Join(split[:len(split)-2], "§")
+	if rawJavaResponse.Players.Online, err = strconv.Atoi(split[len(split)-2]); err != nil {
+		return rawJavaResponse, err
+	}
+	if rawJavaResponse.Players.Max, err = strconv.Atoi(split[len(split)-1]); err != nil {
+		return rawJavaResponse, err
+	}
+
+	return rawJavaResponse, nil
+}
+
+// writeLegacyString writes a UTF-16BE string prefixed with its length in characters
+func writeLegacyString(val string, w io.Writer) error {
+	encoded := utf16.Encode([]rune(val))
+
+	if err := binary.Write(w, binary.BigEndian, uint16(len(encoded))); err != nil {
+		return err
+	}
+
+	return binary.Write(w, binary.BigEndian, encoded)
+}
+
+func readLegacyString(r io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+
+	encoded := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, encoded); err != nil {
+		return "", err
+	}
+
+	return string(utf16.Decode(encoded)), nil
+}
@@ -0,0 +1,178 @@
+package torch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+
+	"torch/src/structs"
+	"torch/src/utils"
+)
+
+// forgeMarkers are the values of Options.ForgeMarker, appended to the handshake host so Forge servers treat the ping as coming from an FML client
+var forgeMarkers = map[string]string{
+	"fml":  "\x00FML\x00",
+	"fml2": "\x00FML2\x00",
+	"fml3": "\x00FML3\x00",
+}
+
+// IsValidForgeMarker reports whether marker is empty or one of the keys of forgeMarkers
+func IsValidForgeMarker(marker string) bool {
+	_, ok := forgeMarkers[marker]
+	return marker == "" || ok
+}
+
+// forgeIgnoreServerOnly is the version Forge reports for mods that don't need to be present on the client
+const forgeIgnoreServerOnly = "OHNOES\U0001F631\U0001F631\U0001F631\U0001F631"
+
+type forgeData struct {
+	Truncated bool
+	Mods      []structs.Mod
+	Channels  []structs.Channel
+}
+
+// decodeForgeData decodes the "d" field used by Forge 1.18+ which packs 15 bits into every UTF-16 character,
+// the first two characters hold the length of the decoded data
+func decodeForgeData(encoded string) (*forgeData, error) {
+	chars := utf16.Encode([]rune(encoded))
+	if len(chars) < 2 {
+		return nil, fmt.Errorf("forge data too short (%d characters)", len(chars))
+	}
+
+	size := int(chars[0]) | int(chars[1])<<15
+	if size > len(chars)*2 {
+		return nil, fmt.Errorf("forge data length %d exceeds encoded data", size)
+	}
+
+	data := make([]byte, 0, size)
+	buffer, bits := 0, 0
+	for _, c := range chars[2:] {
+		for bits >= 8 {
+			data = append(data, byte(buffer))
+			buffer >>= 8
+			bits -= 8
+		}
+		buffer |= int(c&0x7FFF) << bits
+		bits += 15
+	}
+	for len(data) < size {
+		data = append(data, byte(buffer))
+		buffer >>= 8
+		bits -= 8
+	}
+
+	return parseForgeData(bytes.NewReader(data[:size]))
+}
+
+func parseForgeData(r *bytes.Reader) (*forgeData, error) {
+	result := &forgeData{
+		Mods:     make([]structs.Mod, 0),
+		Channels: make([]structs.Channel, 0),
+	}
+
+	truncated, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	result.Truncated = truncated != 0
+
+	var modCount uint16
+	if err := binary.Read(r, binary.BigEndian, &modCount); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < int(modCount); i++ {
+		// The lowest bit flags server-only mods which don't send a version
+		flags, _, err := utils.ReadVarInt(r)
+		if err != nil {
+			return nil, err
+		}
+		channelCount := int(uint32(flags) >> 1)
+
+		modId, err := readForgeString(r)
+		if err != nil {
+			return nil, err
+		}
+
+		version := forgeIgnoreServerOnly
+		if flags&0b1 == 0 {
+			if version, err = readForgeString(r); err != nil {
+				return nil, err
+			}
+		}
+
+		for j := 0; j < channelCount; j++ {
+			channel, err := readForgeChannel(r, modId+":")
+			if err != nil {
+				return nil, err
+			}
+			result.Channels = append(result.Channels, channel)
+		}
+
+		result.Mods = append(result.Mods, structs.Mod{
+			ID:      modId,
+			Version: version,
+		})
+	}
+
+	channelCount, _, err := utils.ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < int(channelCount); i++ {
+		channel, err := readForgeChannel(r, "")
+		if err != nil {
+			return nil, err
+		}
+		result.Channels = append(result.Channels, channel)
+	}
+
+	return result, nil
+}
+
+func readForgeChannel(r *bytes.Reader, prefix string) (structs.Channel, error) {
+	var channel structs.Channel
+
+	name, err := readForgeString(r)
+	if err != nil {
+		return channel, err
+	}
+
+	version, err := readForgeString(r)
+	if err != nil {
+		return channel, err
+	}
+
+	required, err := r.ReadByte()
+	if err != nil {
+		return channel, err
+	}
+
+	channel.Name = prefix + name
+	channel.Version = version
+	channel.Required = required != 0
+
+	return channel, nil
+}
+
+// readForgeString reads a VarInt-prefixed UTF-8 string without trusting the length beyond the remaining data
+func readForgeString(r *bytes.Reader) (string, error) {
+	length, _, err := utils.ReadVarInt(r)
+	if err != nil {
+		return "", err
+	}
+
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("forge string length %d out of range", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
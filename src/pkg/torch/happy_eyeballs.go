@@ -0,0 +1,196 @@
+package torch
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"torch/src/structs"
+)
+
+// connectionAttemptDelay is how long an attempt may run before the next address is tried in parallel (RFC 8305 5)
+const connectionAttemptDelay = 250 * time.Millisecond
+
+type attemptResult[T any] struct {
+	index   int
+	value   T
+	err     error
+	elapsed time.Duration
+}
+
+// raceResult is the winning attempt of raceTarget
+type raceResult[T any] struct {
+	value     T
+	ip        string
+	addresses []structs.AddressResult
+	// dns is the time spent resolving the target, attempt the duration of the winning attempt
+	dns     time.Duration
+	attempt time.Duration
+}
+
+// interleaveAddresses alternates between address families starting with IPv6 (RFC 8305 4)
+func interleaveAddresses(ipv6 []net.IP, ipv4 []net.IP) []net.IP {
+	ips := make([]net.IP, 0, len(ipv6)+len(ipv4))
+	for i := 0; i < len(ipv6) || i < len(ipv4); i++ {
+		if i < len(ipv6) {
+			ips = append(ips, ipv6[i])
+		}
+		if i < len(ipv4) {
+			ips = append(ips, ipv4[i])
+		}
+	}
+	return ips
+}
+
+// raceTarget resolves the target and runs attempt against its addresses, starting the next attempt
+// whenever the previous one failed or connectionAttemptDelay has passed. The first successful attempt
+// wins, the others are cancelled and successful late results are passed to discard. No new attempts
+// are started once ctx is done.
+func raceTarget[T any](ctx context.Context, opts Options, network string, target Target, attempt func(ctx context.Context, address string) (T, error), discard func(T)) (raceResult[T], error) {
+	var race raceResult[T]
+
+	resolveStart := time.Now()
+	ips, err := resolveTarget(ctx, opts, network, target)
+	race.dns = time.Since(resolveStart)
+	if err != nil {
+		return race, err
+	}
+	if len(ips) < 1 {
+		return race, &net.OpError{Op: "dial", Net: network, Err: errNoSuchHost}
+	}
+
+	addresses := make([]structs.AddressResult, len(ips))
+	for i, ip := range ips {
+		addresses[i] = structs.AddressResult{
+			IP:    ip.String(),
+			State: structs.AddressNotAttempted,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan attemptResult[T], len(ips))
+	next, pending := 0, 0
+
+	start := func() {
+		index := next
+		address := net.JoinHostPort(ips[index].String(), strconv.Itoa(int(target.Port)))
+		next++
+		pending++
+		go func() {
+			attemptStart := time.Now()
+			value, err := attempt(ctx, address)
+			results <- attemptResult[T]{index, value, err, time.Since(attemptStart)}
+		}()
+	}
+
+	timer := time.NewTimer(connectionAttemptDelay)
+	defer timer.Stop()
+
+	start()
+	for pending > 0 {
+		select {
+		case result := <-results:
+			pending--
+
+			if result.err == nil {
+				addresses[result.index].State = structs.AddressConnected
+				for i := 0; i < next; i++ {
+					if addresses[i].State == structs.AddressNotAttempted {
+						addresses[i].State = structs.AddressCancelled
+					}
+				}
+				go discardAttempts(results, pending, discard)
+
+				race.value = result.value
+				race.ip = addresses[result.index].IP
+				race.addresses = addresses
+				race.attempt = result.elapsed
+				return race, nil
+			}
+
+			addresses[result.index].State = structs.AddressFailed
+			addresses[result.index].Error = result.err.Error()
+			err = result.err
+
+			if next < len(ips) && ctx.Err() == nil {
+				start()
+				resetTimer(timer, connectionAttemptDelay)
+			}
+		case <-timer.C:
+			if next < len(ips) && ctx.Err() == nil {
+				start()
+				timer.Reset(connectionAttemptDelay)
+			}
+		}
+	}
+
+	race.addresses = addresses
+	return race, err
+}
+
+func resetTimer(timer *time.Timer, duration time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(duration)
+}
+
+// discardAttempts waits for the attempts still running after a winner was found and releases their results
+func discardAttempts[T any](results chan attemptResult[T], pending int, discard func(T)) {
+	for i := 0; i < pending; i++ {
+		result := <-results
+		if result.err == nil && discard != nil {
+			discard(result.value)
+		}
+	}
+}
+
+// dialTarget races TCP or UDP connections to all addresses of the target, TCP connections start with
+// the PROXY header if opts.ProxyProtocol is set
+func dialTarget(ctx context.Context, opts Options, network string, target Target) (raceResult[net.Conn], error) {
+	return raceTarget(ctx, opts, network, target, func(ctx context.Context, address string) (net.Conn, error) {
+		conn, err := opts.dial(ctx, network, address)
+		if err != nil || network != "tcp" {
+			return conn, err
+		}
+
+		if err := writeProxyHeader(ctx, conn, opts); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}, func(conn net.Conn) {
+		conn.Close()
+	})
+}
+
+func remoteIP(conn net.Conn) string {
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// closeOnCancel closes the connection when ctx is cancelled to abort blocking reads, the returned
+// function must be called once the connection is no longer used
+func closeOnCancel(ctx context.Context, conn net.Conn) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
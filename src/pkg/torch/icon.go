@@ -0,0 +1,23 @@
+package torch
+
+import (
+	"context"
+	"time"
+
+	"torch/src/structs"
+)
+
+// FetchIcon returns the icon of a Java server as a data URI, Data is empty if the server has none
+func FetchIcon(ctx context.Context, host string, port uint16, opts Options) (*structs.Icon, error) {
+	status, err := FetchJava(ctx, host, port, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &structs.Icon{
+		Host:       host,
+		Port:       port,
+		Data:       status.Icon,
+		ObtainedAt: time.Now(),
+	}, nil
+}
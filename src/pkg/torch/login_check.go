@@ -0,0 +1,139 @@
+package torch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"torch/src/protocol"
+	"torch/src/structs"
+)
+
+const (
+	loginCheckUsername = "torch"
+	// maxLoginPackets bounds how many packets are read before the login is classified
+	maxLoginPackets = 8
+)
+
+// ErrLoginUnsupported is returned by CheckLogin for servers older than 1.7
+var ErrLoginUnsupported = errors.New("login checks are not supported for servers older than 1.7")
+
+// CheckLogin starts a login to classify whether a Java server runs in online or offline mode, or
+// why it refuses the login. Without opts.ProtocolVersion the version reported by the server is used
+// so it doesn't kick us as outdated.
+func CheckLogin(ctx context.Context, host string, port uint16, opts Options) (*structs.LoginCheck, error) {
+	protocolVersion := opts.ProtocolVersion
+	if protocolVersion <= 0 {
+		status, err := FetchJava(ctx, host, port, opts)
+		if err != nil {
+			return nil, err
+		}
+		if status.PingVersion != structs.PingVersionNetty {
+			return nil, ErrLoginUnsupported
+		}
+		protocolVersion = status.Version.Protocol
+	}
+
+	candidates, _ := javaCandidates(ctx, host, port, opts)
+
+	var err error
+	for _, candidate := range candidates {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var result *structs.LoginCheck
+		result, err = checkLoginTarget(ctx, host, port, candidate, protocolVersion, opts)
+		if err == nil || !isDialError(err) {
+			return result, err
+		}
+	}
+
+	return nil, err
+}
+
+func checkLoginTarget(ctx context.Context, originalHost string, originalPort uint16, target Target, protocolVersion int, opts Options) (*structs.LoginCheck, error) {
+	host, port := target.Host, target.Port
+
+	race, err := dialTarget(ctx, opts, "tcp", target)
+	if err != nil {
+		return nil, err
+	}
+	conn := race.value
+	defer conn.Close()
+	defer closeOnCancel(ctx, conn)()
+
+	if err = conn.SetDeadline(opts.deadline(ctx)); err != nil {
+		return nil, err
+	}
+
+	packetConn := protocol.NewConn(conn, int32(protocolVersion))
+	defer packetConn.Release()
+
+	if err = sendHandshake(packetConn, host, port, protocol.StateLogin); err != nil {
+		return nil, err
+	}
+
+	if err = packetConn.WritePacket(&protocol.LoginStart{Name: loginCheckUsername}); err != nil {
+		return nil, err
+	}
+
+	result := &structs.LoginCheck{
+		Host:       originalHost,
+		Port:       originalPort,
+		Protocol:   protocolVersion,
+		ObtainedAt: time.Now(),
+	}
+
+	for i := 0; i < maxLoginPackets; i++ {
+		packet, err := packetConn.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		switch packet := packet.(type) {
+		case *protocol.Disconnect:
+			var object interface{}
+			if err := json.Unmarshal(packet.Reason, &object); err != nil {
+				object = string(packet.Reason)
+			}
+			result.Result = structs.LoginResultDisconnected
+			result.Reason = structs.Parse(object)
+			result.Whitelisted = isWhitelistReason(string(packet.Reason))
+			return result, nil
+		case *protocol.EncryptionRequest:
+			onlineMode := true
+			result.Result = structs.LoginResultOnlineMode
+			result.OnlineMode = &onlineMode
+			return result, nil
+		// Compression is enabled by the connection, the server may still disconnect us afterwards
+		case *protocol.SetCompression:
+		case *protocol.LoginSuccess:
+			onlineMode := false
+			result.Result = structs.LoginResultOfflineMode
+			result.OnlineMode = &onlineMode
+			return result, nil
+		// e.g. Velocity modern forwarding. Answering that we don't understand it makes the server
+		// disconnect us with an explanation
+		case *protocol.LoginPluginRequest:
+			if err := packetConn.WritePacket(&protocol.LoginPluginResponse{MessageID: packet.MessageID}); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected packet ID during login (got 0x%02x)", packet.ID())
+		}
+	}
+
+	return nil, fmt.Errorf("login was not classified after %d packets", maxLoginPackets)
+}
+
+// isWhitelistReason checks the raw disconnect reason for the vanilla whitelist message and translation key
+func isWhitelistReason(reason string) bool {
+	reason = strings.ToLower(reason)
+	return strings.Contains(reason, "not_whitelisted") ||
+		strings.Contains(reason, "not whitelisted") ||
+		strings.Contains(reason, "not white-listed")
+}
@@ -0,0 +1,121 @@
+package torch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// PROXY protocol versions for Options.ProxyProtocol, see https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+const (
+	ProxyProtocolV1 = "v1"
+	ProxyProtocolV2 = "v2"
+)
+
+// proxyProtocolV2Signature starts every version 2 header
+var proxyProtocolV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+const (
+	// proxyProtocolV2Proxy is version 2 with the PROXY command
+	proxyProtocolV2Proxy = 0x21
+	proxyProtocolV2TCP4  = 0x11
+	proxyProtocolV2TCP6  = 0x21
+)
+
+// IsValidProxyProtocol reports whether version is empty or one of the PROXY protocol versions
+func IsValidProxyProtocol(version string) bool {
+	switch version {
+	case "", ProxyProtocolV1, ProxyProtocolV2:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeProxyHeader sends the PROXY header configured in opts, announcing opts.ProxySource (or the local
+// address of the connection) as the client and the server address as the destination
+func writeProxyHeader(ctx context.Context, conn net.Conn, opts Options) error {
+	if opts.ProxyProtocol == "" {
+		return nil
+	}
+
+	source, ok := conn.LocalAddr().(*net.TCPAddr)
+	if opts.ProxySource != nil {
+		source, ok = opts.ProxySource, true
+	}
+	if !ok {
+		return fmt.Errorf("proxy protocol: unsupported source address %s", conn.LocalAddr())
+	}
+
+	destination, err := net.ResolveTCPAddr("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		return err
+	}
+
+	var header []byte
+	switch opts.ProxyProtocol {
+	case ProxyProtocolV1:
+		header = proxyHeaderV1(source, destination)
+	case ProxyProtocolV2:
+		header = proxyHeaderV2(source, destination)
+	default:
+		return fmt.Errorf("proxy protocol: unknown version %q", opts.ProxyProtocol)
+	}
+
+	if err := conn.SetWriteDeadline(opts.deadline(ctx)); err != nil {
+		return err
+	}
+	_, err = conn.Write(header)
+	return err
+}
+
+// proxyAddresses returns both addresses in the same family, mapping IPv4 into IPv6 if the other one is IPv6
+func proxyAddresses(source *net.TCPAddr, destination *net.TCPAddr) (net.IP, net.IP, bool) {
+	sourceIP, destinationIP := source.IP.To4(), destination.IP.To4()
+	if sourceIP != nil && destinationIP != nil {
+		return sourceIP, destinationIP, true
+	}
+
+	return source.IP.To16(), destination.IP.To16(), false
+}
+
+func proxyHeaderV1(source *net.TCPAddr, destination *net.TCPAddr) []byte {
+	sourceIP, destinationIP, ipv4 := proxyAddresses(source, destination)
+
+	if ipv4 {
+		return []byte(fmt.Sprintf("PROXY TCP4 %s %s %d %d\r\n", sourceIP, destinationIP, source.Port, destination.Port))
+	}
+
+	return []byte(fmt.Sprintf("PROXY TCP6 %s %s %d %d\r\n", proxyIPv6(sourceIP), proxyIPv6(destinationIP), source.Port, destination.Port))
+}
+
+// proxyIPv6 formats the IP in IPv6 notation, which net.IP.String does not use for IPv4-mapped addresses
+func proxyIPv6(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return "::ffff:" + ip4.String()
+	}
+	return ip.String()
+}
+
+func proxyHeaderV2(source *net.TCPAddr, destination *net.TCPAddr) []byte {
+	sourceIP, destinationIP, ipv4 := proxyAddresses(source, destination)
+
+	family := byte(proxyProtocolV2TCP6)
+	if ipv4 {
+		family = proxyProtocolV2TCP4
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write(proxyProtocolV2Signature)
+	buf.WriteByte(proxyProtocolV2Proxy)
+	buf.WriteByte(family)
+	binary.Write(buf, binary.BigEndian, uint16(2*len(sourceIP)+4))
+	buf.Write(sourceIP)
+	buf.Write(destinationIP)
+	binary.Write(buf, binary.BigEndian, uint16(source.Port))
+	binary.Write(buf, binary.BigEndian, uint16(destination.Port))
+
+	return buf.Bytes()
+}
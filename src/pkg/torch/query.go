@@ -0,0 +1,240 @@
+package torch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"torch/src/structs"
+)
+
+var (
+	queryMagic         = []byte{0xFE, 0xFD}
+	queryPlayerSection = []byte{0x01, 'p', 'l', 'a', 'y', 'e', 'r', '_', 0x00, 0x00}
+)
+
+const (
+	queryTypeHandshake = 0x09
+	queryTypeStat      = 0x00
+	// queryPaddingLength is the length of the constant "splitnum\0\x80\0" prefix of a full stat response
+	queryPaddingLength = 11
+)
+
+// FetchQuery retrieves the full stat of a Java server with the GameSpy4 query protocol, which must
+// be enabled with enable-query in server.properties
+func FetchQuery(ctx context.Context, host string, port uint16, opts Options) (*structs.QueryStatus, error) {
+	race, err := raceTarget(ctx, opts, "udp", Target{host, port}, func(ctx context.Context, address string) (*structs.QueryStatus, error) {
+		return queryAddress(ctx, host, port, address, opts)
+	}, nil)
+
+	return race.value, err
+}
+
+func queryAddress(ctx context.Context, host string, port uint16, address string, opts Options) (*structs.QueryStatus, error) {
+	conn, err := opts.dial(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer closeOnCancel(ctx, conn)()
+
+	if err = conn.SetDeadline(opts.deadline(ctx)); err != nil {
+		return nil, err
+	}
+
+	// Only the lower 4 bits of each byte are used by the server
+	sessionId := int32(time.Now().UnixNano()) & 0x0F0F0F0F
+	pingStart := time.Now()
+
+	token, err := queryHandshake(conn, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	// The handshake is answered immediately, unlike the full stat which the server has to assemble
+	latency := structs.NewLatency([]time.Duration{time.Since(pingStart)})
+
+	response, err := queryFullStat(conn, sessionId, token)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := parseQueryFullStat(response)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Host = host
+	status.Port = port
+	status.Latency = latency
+	status.ObtainedAt = time.Now()
+
+	return status, nil
+}
+
+func writeQueryRequest(conn net.Conn, packetType byte, sessionId int32, payload []byte) error {
+	buf := &bytes.Buffer{}
+
+	if _, err := buf.Write(queryMagic); err != nil {
+		return err
+	}
+
+	if err := buf.WriteByte(packetType); err != nil {
+		return err
+	}
+
+	if err := binary.Write(buf, binary.BigEndian, sessionId); err != nil {
+		return err
+	}
+
+	if _, err := buf.Write(payload); err != nil {
+		return err
+	}
+
+	_, err := conn.Write(buf.Bytes())
+	return err
+}
+
+// readQueryResponse reads a single datagram and returns its payload after validating the header
+func readQueryResponse(conn net.Conn, packetType byte, sessionId int32) ([]byte, error) {
+	data := make([]byte, 65535)
+	n, err := conn.Read(data)
+	if err != nil {
+		return nil, err
+	}
+	data = data[:n]
+
+	if len(data) < 5 {
+		return nil, fmt.Errorf("query response too short (%d bytes)", len(data))
+	}
+
+	if data[0] != packetType {
+		return nil, fmt.Errorf("unexpected packet type (expected 0x%02x, got 0x%02x)", packetType, data[0])
+	}
+
+	if returnedSessionId := int32(binary.BigEndian.Uint32(data[1:5])); returnedSessionId != sessionId {
+		return nil, fmt.Errorf("unexpected session ID (expected %d, got %d)", sessionId, returnedSessionId)
+	}
+
+	return data[5:], nil
+}
+
+func queryHandshake(conn net.Conn, sessionId int32) (int32, error) {
+	if err := writeQueryRequest(conn, queryTypeHandshake, sessionId, nil); err != nil {
+		return 0, err
+	}
+
+	payload, err := readQueryResponse(conn, queryTypeHandshake, sessionId)
+	if err != nil {
+		return 0, err
+	}
+
+	// The challenge token is sent as a null-terminated decimal string
+	token, err := strconv.ParseInt(string(bytes.TrimRight(payload, "\x00")), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(token), nil
+}
+
+func queryFullStat(conn net.Conn, sessionId int32, token int32) ([]byte, error) {
+	payload := &bytes.Buffer{}
+
+	if err := binary.Write(payload, binary.BigEndian, token); err != nil {
+		return nil, err
+	}
+
+	// Padding, requests a full stat instead of a basic stat
+	if _, err := payload.Write([]byte{0x00, 0x00, 0x00, 0x00}); err != nil {
+		return nil, err
+	}
+
+	if err := writeQueryRequest(conn, queryTypeStat, sessionId, payload.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return readQueryResponse(conn, queryTypeStat, sessionId)
+}
+
+func parseQueryFullStat(data []byte) (*structs.QueryStatus, error) {
+	if len(data) < queryPaddingLength {
+		return nil, fmt.Errorf("query response too short (%d bytes)", len(data))
+	}
+	reader := bytes.NewBuffer(data[queryPaddingLength:])
+
+	values := make(map[string]string)
+	for {
+		key, err := reader.ReadString(0x00)
+		if err != nil {
+			return nil, err
+		}
+		key = strings.TrimSuffix(key, "\x00")
+		if key == "" {
+			break
+		}
+
+		value, err := reader.ReadString(0x00)
+		if err != nil {
+			return nil, err
+		}
+		values[key] = strings.TrimSuffix(value, "\x00")
+	}
+
+	players := make([]string, 0)
+	if bytes.HasPrefix(reader.Bytes(), queryPlayerSection) {
+		reader.Next(len(queryPlayerSection))
+		for {
+			name, err := reader.ReadString(0x00)
+			name = strings.TrimSuffix(name, "\x00")
+			if err != nil || name == "" {
+				break
+			}
+			players = append(players, name)
+		}
+	}
+
+	status := &structs.QueryStatus{
+		MOTD:     structs.Parse(values["hostname"]),
+		GameType: values["gametype"],
+		GameID:   values["game_id"],
+		Version:  values["version"],
+		Map:      values["map"],
+		HostIP:   values["hostip"],
+		Plugins:  make([]string, 0),
+		Players: structs.QueryPlayers{
+			List: players,
+		},
+	}
+
+	// Plugins are reported as "<software>: <plugin>; <plugin>"
+	if plugins := values["plugins"]; plugins != "" {
+		software, list, found := strings.Cut(plugins, ":")
+		status.Software = strings.TrimSpace(software)
+		if found {
+			for _, plugin := range strings.Split(list, ";") {
+				if plugin = strings.TrimSpace(plugin); plugin != "" {
+					status.Plugins = append(status.Plugins, plugin)
+				}
+			}
+		}
+	}
+
+	var err error
+	if status.Players.Online, err = strconv.Atoi(values["numplayers"]); err != nil {
+		return nil, err
+	}
+	if status.Players.Max, err = strconv.Atoi(values["maxplayers"]); err != nil {
+		return nil, err
+	}
+	if hostPort, err := strconv.ParseUint(values["hostport"], 10, 16); err == nil {
+		status.HostPort = uint16(hostPort)
+	}
+
+	return status, nil
+}
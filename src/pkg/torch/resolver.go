@@ -0,0 +1,494 @@
+package torch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"torch/src/structs"
+
+	"github.com/muesli/cache2go"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resolver looks up the DNS records needed to reach a server, along with their TTL in seconds
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) ([]*net.SRV, uint32, error)
+	LookupHost(ctx context.Context, host string) (*structs.Dns, error)
+}
+
+var errNoSuchHost = errors.New("no such host")
+
+// Records are cached for their TTL clamped to these bounds
+const (
+	dnsMinTTL = 5 * time.Second
+	dnsMaxTTL = 30 * time.Minute
+)
+
+// maxCNAMEChain bounds how many CNAME records are followed for a single lookup
+const maxCNAMEChain = 8
+
+// systemTTL is how long records looked up by the system resolver are cached, as it doesn't report TTLs
+const systemTTL = 60
+
+// dnsResolver sends queries directly to a single DNS server over UDP or TCP, UDP responses that
+// are truncated are retried over TCP
+type dnsResolver struct {
+	server  string
+	network string
+	dialer  Dialer
+}
+
+// NewResolver returns a caching resolver for the given server (host or host:port), queried over
+// network (udp or tcp, udp if empty). Without a server the system resolver is used, which honours
+// /etc/hosts and search domains but doesn't report TTLs, so its records are cached for a minute.
+//
+// Queries are sent through dialer unless it is nil, so they share the source address of the pings.
+// Through a SOCKS5Dialer they are sent over TCP, as not every proxy supports UDP ASSOCIATE.
+func NewResolver(server string, network string, dialer Dialer) Resolver {
+	_, proxied := dialer.(*SOCKS5Dialer)
+
+	if server == "" {
+		resolver := net.DefaultResolver
+		if dialer != nil {
+			resolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					// The Go resolver speaks TCP on connections that are not a net.PacketConn
+					if proxied {
+						network = "tcp"
+					}
+					return dialer.DialContext(ctx, network, address)
+				},
+			}
+		}
+
+		return &cachingResolver{
+			resolver: &systemResolver{resolver},
+			cache:    cache2go.Cache("dns/system"),
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	switch {
+	case proxied:
+		network = "tcp"
+	case network != "tcp":
+		network = "udp"
+	}
+
+	if dialer == nil {
+		dialer = NewDialer(nil)
+	}
+
+	return &cachingResolver{
+		resolver: &dnsResolver{server, network, dialer},
+		cache:    cache2go.Cache("dns/" + network + "/" + server),
+	}
+}
+
+// systemResolver looks records up through a net.Resolver, reporting systemTTL as their TTL
+type systemResolver struct {
+	resolver *net.Resolver
+}
+
+func (r *systemResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]*net.SRV, uint32, error) {
+	_, addrs, err := r.resolver.LookupSRV(ctx, service, proto, name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return nil, 0, errNoSuchHost
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return addrs, systemTTL, nil
+}
+
+func (r *systemResolver) LookupHost(ctx context.Context, host string) (*structs.Dns, error) {
+	result := &structs.Dns{
+		Host:   host,
+		CNAMEs: make([]string, 0),
+		A:      make([]string, 0),
+		AAAA:   make([]string, 0),
+		TTL:    systemTTL,
+	}
+
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			result.A = append(result.A, addr.IP.String())
+		} else {
+			result.AAAA = append(result.AAAA, addr.IP.String())
+		}
+	}
+
+	// Only the end of a CNAME chain is known to the system resolver
+	if net.ParseIP(host) == nil {
+		if cname, err := r.resolver.LookupCNAME(ctx, host); err == nil && !strings.EqualFold(cname, fqdn(host)) {
+			result.CNAMEs = append(result.CNAMEs, strings.TrimSuffix(cname, "."))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *dnsResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]*net.SRV, uint32, error) {
+	msg, err := r.query(ctx, fmt.Sprintf("_%s._%s.%s", service, proto, name), dnsmessage.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	addrs := make([]*net.SRV, 0)
+	ttl := uint32(0)
+	for _, answer := range msg.Answers {
+		if record, ok := answer.Body.(*dnsmessage.SRVResource); ok {
+			addrs = append(addrs, &net.SRV{
+				Target:   record.Target.String(),
+				Port:     record.Port,
+				Priority: record.Priority,
+				Weight:   record.Weight,
+			})
+			ttl = minTTL(ttl, answer.Header.TTL)
+		}
+	}
+
+	return addrs, ttl, nil
+}
+
+func (r *dnsResolver) LookupHost(ctx context.Context, host string) (*structs.Dns, error) {
+	result := &structs.Dns{
+		Host:   host,
+		CNAMEs: make([]string, 0),
+		A:      make([]string, 0),
+		AAAA:   make([]string, 0),
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.To4() != nil {
+			result.A = append(result.A, ip.String())
+		} else {
+			result.AAAA = append(result.AAAA, ip.String())
+		}
+		return result, nil
+	}
+
+	var wg sync.WaitGroup
+	var a, aaaa *dnsmessage.Message
+	var aErr, aaaaErr error
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a, aErr = r.query(ctx, host, dnsmessage.TypeA)
+	}()
+	go func() {
+		defer wg.Done()
+		aaaa, aaaaErr = r.query(ctx, host, dnsmessage.TypeAAAA)
+	}()
+	wg.Wait()
+
+	if aErr != nil && aaaaErr != nil {
+		return nil, aErr
+	}
+
+	for _, msg := range []*dnsmessage.Message{a, aaaa} {
+		if msg == nil {
+			continue
+		}
+
+		chain, target, ttl := followCNAMEs(msg, host)
+		if len(chain) > len(result.CNAMEs) {
+			result.CNAMEs = chain
+		}
+
+		for _, answer := range msg.Answers {
+			if !strings.EqualFold(answer.Header.Name.String(), target) {
+				continue
+			}
+			switch record := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				result.A = append(result.A, net.IP(record.A[:]).String())
+				ttl = minTTL(ttl, answer.Header.TTL)
+			case *dnsmessage.AAAAResource:
+				result.AAAA = append(result.AAAA, net.IP(record.AAAA[:]).String())
+				ttl = minTTL(ttl, answer.Header.TTL)
+			}
+		}
+
+		result.TTL = minTTL(result.TTL, ttl)
+	}
+
+	if len(result.A) < 1 && len(result.AAAA) < 1 {
+		return nil, errNoSuchHost
+	}
+
+	return result, nil
+}
+
+// followCNAMEs walks the CNAME records in the answer section starting at host, returning the chain,
+// the final name and the lowest TTL along the chain
+func followCNAMEs(msg *dnsmessage.Message, host string) ([]string, string, uint32) {
+	chain := make([]string, 0)
+	name := fqdn(host)
+	ttl := uint32(0)
+
+	for i := 0; i < maxCNAMEChain; i++ {
+		found := false
+		for _, answer := range msg.Answers {
+			record, ok := answer.Body.(*dnsmessage.CNAMEResource)
+			if ok && strings.EqualFold(answer.Header.Name.String(), name) {
+				name = record.CNAME.String()
+				chain = append(chain, strings.TrimSuffix(name, "."))
+				ttl = minTTL(ttl, answer.Header.TTL)
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+
+	return chain, name, ttl
+}
+
+func (r *dnsResolver) query(ctx context.Context, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	msg, err := r.exchange(ctx, r.network, name, qtype)
+	if err == nil && msg.Header.Truncated && r.network == "udp" {
+		msg, err = r.exchange(ctx, "tcp", name, qtype)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch msg.Header.RCode {
+	case dnsmessage.RCodeSuccess:
+		return msg, nil
+	case dnsmessage.RCodeNameError:
+		return nil, errNoSuchHost
+	default:
+		return nil, fmt.Errorf("dns: server responded with %s", msg.Header.RCode)
+	}
+}
+
+func (r *dnsResolver) exchange(ctx context.Context, network string, name string, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	question, err := dnsmessage.NewName(fqdn(name))
+	if err != nil {
+		return nil, err
+	}
+
+	request := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               uint16(rand.Intn(1 << 16)),
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{{
+			Name:  question,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, false); err != nil {
+		return nil, err
+	}
+	request.Additionals = append(request.Additionals, dnsmessage.Resource{Header: opt, Body: &dnsmessage.OPTResource{}})
+
+	packed, err := request.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.dialer.DialContext(ctx, network, r.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(DefaultTimeout)
+	}
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var response []byte
+	if network == "tcp" {
+		response, err = exchangeTCP(conn, packed)
+	} else {
+		response, err = exchangeUDP(conn, packed)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var msg dnsmessage.Message
+	if err := msg.Unpack(response); err != nil {
+		return nil, err
+	}
+
+	if msg.Header.ID != request.Header.ID || !msg.Header.Response {
+		return nil, errors.New("dns: response does not match the query")
+	}
+	if len(msg.Questions) != 1 || msg.Questions[0].Type != qtype || !strings.EqualFold(msg.Questions[0].Name.String(), question.String()) {
+		return nil, errors.New("dns: response is for a different question")
+	}
+
+	return &msg, nil
+}
+
+func exchangeUDP(conn net.Conn, packed []byte) ([]byte, error) {
+	if _, err := conn.Write(packed); err != nil {
+		return nil, err
+	}
+
+	response := make([]byte, 65535)
+	n, err := conn.Read(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response[:n], nil
+}
+
+// exchangeTCP frames the message with its length as described in RFC 1035 4.2.2
+func exchangeTCP(conn net.Conn, packed []byte) ([]byte, error) {
+	framed := make([]byte, 2+len(packed))
+	binary.BigEndian.PutUint16(framed, uint16(len(packed)))
+	copy(framed[2:], packed)
+
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+
+	var length uint16
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	response := make([]byte, length)
+	if _, err := io.ReadFull(conn, response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// cachingResolver caches lookups for the TTL of their records, clamped between dnsMinTTL and dnsMaxTTL
+type cachingResolver struct {
+	resolver Resolver
+	cache    *cache2go.CacheTable
+}
+
+type srvLookup struct {
+	addrs []*net.SRV
+	ttl   uint32
+	err   error
+}
+
+func (r *cachingResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]*net.SRV, uint32, error) {
+	cacheKey := fmt.Sprintf("srv/%s/%s/%s", service, proto, name)
+	if data, err := r.cache.Value(cacheKey); err == nil {
+		lookup := data.Data().(*srvLookup)
+		return lookup.addrs, lookup.ttl, lookup.err
+	}
+
+	addrs, ttl, err := r.resolver.LookupSRV(ctx, service, proto, name)
+	if err != nil && err != errNoSuchHost {
+		return nil, 0, err
+	}
+
+	// Missing records are cached as well so servers without SRV don't cause a query on every ping
+	r.cache.Add(cacheKey, clampTTL(ttl), &srvLookup{addrs, ttl, err})
+	return addrs, ttl, err
+}
+
+func (r *cachingResolver) LookupHost(ctx context.Context, host string) (*structs.Dns, error) {
+	cacheKey := "host/" + host
+	if data, err := r.cache.Value(cacheKey); err == nil {
+		return data.Data().(*structs.Dns), nil
+	}
+
+	result, err := r.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	result.ObtainedAt = time.Now()
+	result.ExpiresAt = time.Now().Add(clampTTL(result.TTL))
+
+	r.cache.Add(cacheKey, clampTTL(result.TTL), result)
+	return result, nil
+}
+
+func clampTTL(ttl uint32) time.Duration {
+	duration := time.Duration(ttl) * time.Second
+	if duration < dnsMinTTL {
+		return dnsMinTTL
+	}
+	if duration > dnsMaxTTL {
+		return dnsMaxTTL
+	}
+	return duration
+}
+
+// minTTL returns the lower TTL, treating 0 as unset
+func minTTL(a, b uint32) uint32 {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+func fqdn(name string) string {
+	if strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
+
+// resolveTarget returns the addresses of the target, errors are reported as dial errors so SRV
+// targets that don't resolve are failed over like unreachable ones
+func resolveTarget(ctx context.Context, opts Options, network string, target Target) ([]net.IP, error) {
+	result, err := LookupHost(ctx, target.Host, opts)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+
+	ipv6 := make([]net.IP, 0)
+	for _, addr := range result.AAAA {
+		ipv6 = append(ipv6, net.ParseIP(addr))
+	}
+
+	ipv4 := make([]net.IP, 0)
+	for _, addr := range result.A {
+		ipv4 = append(ipv4, net.ParseIP(addr))
+	}
+
+	return interleaveAddresses(ipv6, ipv4), nil
+}
+
+// LookupHost looks up the A and AAAA records of host, following CNAME records
+func LookupHost(ctx context.Context, host string, opts Options) (*structs.Dns, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	return opts.resolver().LookupHost(ctx, host)
+}
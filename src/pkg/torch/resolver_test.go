@@ -0,0 +1,340 @@
+package torch
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"torch/src/structs"
+
+	"github.com/muesli/cache2go"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// stubDNS answers queries over UDP and TCP on the same port with the records returned by answer
+type stubDNS struct {
+	addr       string
+	udp        net.PacketConn
+	tcp        net.Listener
+	tcpQueries atomic.Int32
+
+	// answer returns the answer section for a question and whether the UDP response is truncated
+	answer func(question dnsmessage.Question) ([]dnsmessage.Resource, bool)
+}
+
+func newStubDNS(t *testing.T, answer func(question dnsmessage.Question) ([]dnsmessage.Resource, bool)) *stubDNS {
+	t.Helper()
+
+	s := &stubDNS{answer: answer}
+
+	// The TCP listener needs the port of the UDP one, which may be taken
+	for attempt := 0; s.tcp == nil; attempt++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			udp.Close()
+			if attempt > 10 {
+				t.Fatal(err)
+			}
+			continue
+		}
+		s.udp, s.tcp, s.addr = udp, tcp, udp.LocalAddr().String()
+	}
+	t.Cleanup(func() {
+		s.udp.Close()
+		s.tcp.Close()
+	})
+
+	go s.serveUDP()
+	go s.serveTCP()
+
+	return s
+}
+
+func (s *stubDNS) serveUDP() {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if response, err := s.respond(buf[:n], false); err == nil {
+			s.udp.WriteTo(response, addr)
+		}
+	}
+}
+
+func (s *stubDNS) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		s.tcpQueries.Add(1)
+
+		go func() {
+			defer conn.Close()
+
+			var length uint16
+			if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+				return
+			}
+			query := make([]byte, length)
+			if _, err := io.ReadFull(conn, query); err != nil {
+				return
+			}
+
+			response, err := s.respond(query, true)
+			if err != nil {
+				return
+			}
+			framed := binary.BigEndian.AppendUint16(nil, uint16(len(response)))
+			conn.Write(append(framed, response...))
+		}()
+	}
+}
+
+func (s *stubDNS) respond(query []byte, tcp bool) ([]byte, error) {
+	var request dnsmessage.Message
+	if err := request.Unpack(query); err != nil {
+		return nil, err
+	}
+
+	answers, truncated := s.answer(request.Questions[0])
+	response := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 request.Header.ID,
+			Response:           true,
+			RecursionAvailable: true,
+		},
+		Questions: request.Questions,
+	}
+
+	if truncated && !tcp {
+		response.Header.Truncated = true
+	} else {
+		response.Answers = answers
+	}
+
+	return response.Pack()
+}
+
+func resourceHeader(name string, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Class: dnsmessage.ClassINET,
+		TTL:   ttl,
+	}
+}
+
+func cnameRecord(name string, target string, ttl uint32) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: resourceHeader(name, ttl),
+		Body:   &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(target)},
+	}
+}
+
+func aRecord(name string, ip string, ttl uint32) dnsmessage.Resource {
+	var a [4]byte
+	copy(a[:], net.ParseIP(ip).To4())
+	return dnsmessage.Resource{
+		Header: resourceHeader(name, ttl),
+		Body:   &dnsmessage.AResource{A: a},
+	}
+}
+
+func TestDNSResolverCNAMEChain(t *testing.T) {
+	stub := newStubDNS(t, func(question dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+		answers := []dnsmessage.Resource{
+			cnameRecord("play.example.test.", "edge.example.test.", 300),
+			cnameRecord("edge.example.test.", "node.example.test.", 120),
+		}
+		if question.Type == dnsmessage.TypeA {
+			answers = append(answers, aRecord("node.example.test.", "192.0.2.10", 600))
+		}
+		return answers, false
+	})
+
+	resolver := &dnsResolver{stub.addr, "udp", NewDialer(nil)}
+	result, err := resolver.LookupHost(context.Background(), "play.example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.CNAMEs) != 2 || result.CNAMEs[0] != "edge.example.test" || result.CNAMEs[1] != "node.example.test" {
+		t.Errorf("CNAMEs = %v, want [edge.example.test node.example.test]", result.CNAMEs)
+	}
+	if len(result.A) != 1 || result.A[0] != "192.0.2.10" {
+		t.Errorf("A = %v, want [192.0.2.10]", result.A)
+	}
+	if len(result.AAAA) != 0 {
+		t.Errorf("AAAA = %v, want none", result.AAAA)
+	}
+	// The lowest TTL along the chain applies
+	if result.TTL != 120 {
+		t.Errorf("TTL = %d, want 120", result.TTL)
+	}
+}
+
+func TestDNSResolverTruncatedRetriesOverTCP(t *testing.T) {
+	stub := newStubDNS(t, func(question dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+		if question.Type != dnsmessage.TypeA {
+			return nil, false
+		}
+		return []dnsmessage.Resource{aRecord("big.example.test.", "192.0.2.20", 60)}, true
+	})
+
+	resolver := &dnsResolver{stub.addr, "udp", NewDialer(nil)}
+	result, err := resolver.LookupHost(context.Background(), "big.example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.A) != 1 || result.A[0] != "192.0.2.20" {
+		t.Errorf("A = %v, want [192.0.2.20]", result.A)
+	}
+	if queries := stub.tcpQueries.Load(); queries != 1 {
+		t.Errorf("%d queries over TCP, want 1", queries)
+	}
+}
+
+func TestCachingResolverClampsTTL(t *testing.T) {
+	tests := []struct {
+		host string
+		ttl  uint32
+		want time.Duration
+	}{
+		{"short.example.test", 1, dnsMinTTL},
+		{"normal.example.test", 300, 300 * time.Second},
+		{"long.example.test", 7 * 24 * 60 * 60, dnsMaxTTL},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.host, func(t *testing.T) {
+			var queries atomic.Int32
+			stub := newStubDNS(t, func(question dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+				queries.Add(1)
+				if question.Type != dnsmessage.TypeA {
+					return nil, false
+				}
+				return []dnsmessage.Resource{aRecord(test.host+".", "192.0.2.30", test.ttl)}, false
+			})
+
+			resolver := NewResolver(stub.addr, "udp", nil)
+			result, err := resolver.LookupHost(context.Background(), test.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cached := result.ExpiresAt.Sub(result.ObtainedAt).Round(time.Second); cached != test.want {
+				t.Errorf("cached for %s, want %s", cached, test.want)
+			}
+
+			if _, err := resolver.LookupHost(context.Background(), test.host); err != nil {
+				t.Fatal(err)
+			}
+			// A and AAAA of the first lookup, the second one is served from the cache
+			if count := queries.Load(); count != 2 {
+				t.Errorf("%d queries, want 2", count)
+			}
+		})
+	}
+}
+
+func TestDNSResolverNoAddresses(t *testing.T) {
+	stub := newStubDNS(t, func(question dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+		return nil, false
+	})
+
+	resolver := &dnsResolver{stub.addr, "udp", NewDialer(nil)}
+	if _, err := resolver.LookupHost(context.Background(), "missing.example.test"); err != errNoSuchHost {
+		t.Errorf("err = %v, want %v", err, errNoSuchHost)
+	}
+}
+
+func TestSystemResolverUsesHostsFile(t *testing.T) {
+	result, err := NewResolver("", "", nil).LookupHost(context.Background(), "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.A)+len(result.AAAA) < 1 {
+		t.Errorf("localhost resolved to no addresses")
+	}
+}
+
+// recordingDialer dials directly, recording the networks it was asked for
+type recordingDialer struct {
+	networks chan string
+}
+
+func (d *recordingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.networks <- network
+	return NewDialer(nil).DialContext(ctx, network, address)
+}
+
+func TestResolverQueriesThroughDialer(t *testing.T) {
+	stub := newStubDNS(t, func(question dnsmessage.Question) ([]dnsmessage.Resource, bool) {
+		if question.Type != dnsmessage.TypeA {
+			return nil, false
+		}
+		return []dnsmessage.Resource{aRecord("dialed.example.test.", "192.0.2.40", 60)}, false
+	})
+
+	dialer := &recordingDialer{make(chan string, 2)}
+	if _, err := NewResolver(stub.addr, "udp", dialer).LookupHost(context.Background(), "dialed.example.test"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if network := <-dialer.networks; network != "udp" {
+			t.Errorf("dialed %s, want udp", network)
+		}
+	}
+}
+
+func TestResolverUsesTCPThroughSOCKS5(t *testing.T) {
+	resolver := NewResolver("192.0.2.53", "udp", &SOCKS5Dialer{Address: "127.0.0.1:1080"}).(*cachingResolver)
+
+	if network := resolver.resolver.(*dnsResolver).network; network != "tcp" {
+		t.Errorf("network = %s, want tcp", network)
+	}
+}
+
+// missingSRVResolver has no SRV records, counting the lookups
+type missingSRVResolver struct {
+	lookups int
+}
+
+func (r *missingSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]*net.SRV, uint32, error) {
+	r.lookups++
+	return nil, 0, errNoSuchHost
+}
+
+func (r *missingSRVResolver) LookupHost(ctx context.Context, host string) (*structs.Dns, error) {
+	return nil, errNoSuchHost
+}
+
+func TestCachingResolverCachesMissingSRV(t *testing.T) {
+	missing := &missingSRVResolver{}
+	resolver := &cachingResolver{missing, cache2go.Cache(t.Name())}
+
+	for i := 0; i < 3; i++ {
+		addrs, _, err := resolver.LookupSRV(context.Background(), "minecraft", "tcp", "example.test")
+		if len(addrs) != 0 || err != errNoSuchHost {
+			t.Errorf("lookup %d = %v, %v, want no records and %v", i, addrs, err, errNoSuchHost)
+		}
+	}
+
+	if missing.lookups != 1 {
+		t.Errorf("%d lookups, want 1", missing.lookups)
+	}
+}
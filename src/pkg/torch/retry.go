@@ -0,0 +1,51 @@
+package torch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"time"
+)
+
+// retry runs lookup until it succeeds, opts.Retries retries failed or an error is not retryable,
+// waiting with exponential backoff between attempts. It returns the number of attempts made.
+func retry[T any](ctx context.Context, opts Options, retryable func(context.Context, error) bool, lookup func() (T, error)) (T, int, error) {
+	backoff := opts.retryBackoff()
+
+	for attempt := 1; ; attempt++ {
+		result, err := lookup()
+		if err == nil || attempt > opts.Retries || !retryable(ctx, err) {
+			return result, attempt, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, attempt, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff *= 2
+	}
+}
+
+// isRetryable reports whether err may be temporary, hosts that don't exist are not retried
+func isRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, errNoSuchHost) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	return !errors.As(err, &dnsErr) || !dnsErr.IsNotFound
+}
+
+// isJavaRetryable is isRetryable for Java pings, which are not retried once a connection was opened but
+// the server stayed silent until the read timeout, as it would most likely do so again
+func isJavaRetryable(ctx context.Context, err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) && !isDialError(err) {
+		return false
+	}
+	return isRetryable(ctx, err)
+}
@@ -0,0 +1,425 @@
+package torch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SOCKS5 constants from RFC 1928 and RFC 1929
+const (
+	socks5Version = 0x05
+
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthUnacceptable = 0xFF
+	socks5PasswordVersion  = 0x01
+
+	socks5CommandConnect      = 0x01
+	socks5CommandUDPAssociate = 0x03
+
+	socks5AddressIPv4   = 0x01
+	socks5AddressDomain = 0x03
+	socks5AddressIPv6   = 0x04
+)
+
+var socks5Replies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// SOCKS5Dialer connects through a SOCKS5 proxy, using CONNECT for TCP and UDP ASSOCIATE for UDP
+type SOCKS5Dialer struct {
+	// Address is the host:port of the proxy
+	Address string
+	// Username and Password are sent if Username is set (RFC 1929)
+	Username string
+	Password string
+	// Forward connects to the proxy and its UDP relay, a direct dialer if nil
+	Forward Dialer
+}
+
+// DialContext connects to address through the proxy. Host names are sent to the proxy to resolve, so
+// that no lookup leaks from this host.
+func (d *SOCKS5Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	remote, err := socks5RemoteAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.HasPrefix(network, "tcp"):
+		conn, _, err := d.request(ctx, socks5CommandConnect, address)
+		if err != nil {
+			return nil, err
+		}
+		return &socks5Conn{Conn: conn, remote: remote}, nil
+	case strings.HasPrefix(network, "udp"):
+		return d.associate(ctx, remote)
+	default:
+		return nil, fmt.Errorf("socks5: unsupported network %q", network)
+	}
+}
+
+// socks5RemoteAddr returns the address of the server, a *net.TCPAddr or *net.UDPAddr for IP literals
+// and a socks5HostAddr for host names
+func socks5RemoteAddr(network, address string) (net.Addr, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("socks5: invalid port %q", portString)
+	}
+
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return &socks5HostAddr{network: network, host: host, port: int(port)}, nil
+	case strings.HasPrefix(network, "udp"):
+		return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+	default:
+		return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+	}
+}
+
+// socks5HostAddr is the address of a server known by its host name, which only the proxy resolves
+type socks5HostAddr struct {
+	network string
+	host    string
+	port    int
+}
+
+func (a *socks5HostAddr) Network() string {
+	return a.network
+}
+
+func (a *socks5HostAddr) String() string {
+	return net.JoinHostPort(a.host, strconv.Itoa(a.port))
+}
+
+func (d *SOCKS5Dialer) forward() Dialer {
+	if d.Forward != nil {
+		return d.Forward
+	}
+	return NewDialer(nil)
+}
+
+// request connects to the proxy and sends a command, returning the control connection and the
+// address the proxy bound for the command
+func (d *SOCKS5Dialer) request(ctx context.Context, command byte, address string) (net.Conn, *net.UDPAddr, error) {
+	conn, err := d.forward().DialContext(ctx, "tcp", d.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	}
+	stop := closeOnCancel(ctx, conn)
+
+	bound, err := d.handshake(conn, command, address)
+	stop()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = conn.SetDeadline(time.Time{})
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, bound, nil
+}
+
+func (d *SOCKS5Dialer) handshake(conn net.Conn, command byte, address string) (*net.UDPAddr, error) {
+	method := byte(socks5AuthNone)
+	if d.Username != "" {
+		method = socks5AuthPassword
+	}
+
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] != socks5Version {
+		return nil, fmt.Errorf("socks5: unexpected version %d", reply[0])
+	}
+	if reply[1] == socks5AuthUnacceptable || reply[1] != method {
+		return nil, errors.New("socks5: no acceptable authentication method")
+	}
+
+	if method == socks5AuthPassword {
+		if err := d.authenticate(conn); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{socks5Version, command, 0x00})
+	if err := writeSocks5Address(buf, address); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 3)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+	if header[0] != socks5Version {
+		return nil, fmt.Errorf("socks5: unexpected version %d", header[0])
+	}
+	if header[1] != 0x00 {
+		if reason, ok := socks5Replies[header[1]]; ok {
+			return nil, fmt.Errorf("socks5: %s", reason)
+		}
+		return nil, fmt.Errorf("socks5: request failed with reply 0x%02x", header[1])
+	}
+
+	return readSocks5Address(conn)
+}
+
+func (d *SOCKS5Dialer) authenticate(conn net.Conn) error {
+	if len(d.Username) > 255 || len(d.Password) > 255 {
+		return errors.New("socks5: username and password must not be longer than 255 bytes")
+	}
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte(socks5PasswordVersion)
+	buf.WriteByte(byte(len(d.Username)))
+	buf.WriteString(d.Username)
+	buf.WriteByte(byte(len(d.Password)))
+	buf.WriteString(d.Password)
+	if _, err := conn.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New("socks5: authentication failed")
+	}
+
+	return nil
+}
+
+// associate asks the proxy to relay UDP datagrams to remote. The association lasts as long as the
+// control connection, which is closed along with the returned connection.
+func (d *SOCKS5Dialer) associate(ctx context.Context, remote net.Addr) (net.Conn, error) {
+	// The client address is not known before the relay socket is opened, 0.0.0.0:0 lets the proxy
+	// accept datagrams from any port of this host
+	control, relay, err := d.request(ctx, socks5CommandUDPAssociate, "0.0.0.0:0")
+	if err != nil {
+		return nil, err
+	}
+
+	// Proxies commonly answer with an unspecified address, meaning the relay is on the proxy host
+	if relay.IP.IsUnspecified() {
+		proxyHost, _, err := net.SplitHostPort(d.Address)
+		if err != nil {
+			control.Close()
+			return nil, err
+		}
+		proxyAddr, err := net.ResolveIPAddr("ip", proxyHost)
+		if err != nil {
+			control.Close()
+			return nil, err
+		}
+		relay.IP = proxyAddr.IP
+	}
+
+	conn, err := d.forward().DialContext(ctx, "udp", relay.String())
+	if err != nil {
+		control.Close()
+		return nil, err
+	}
+
+	header := &bytes.Buffer{}
+	// RSV and FRAG, fragmentation is not supported
+	header.Write([]byte{0x00, 0x00, 0x00})
+	if err := writeSocks5Address(header, remote.String()); err != nil {
+		control.Close()
+		conn.Close()
+		return nil, err
+	}
+
+	return &socks5PacketConn{Conn: conn, control: control, remote: remote, header: header.Bytes()}, nil
+}
+
+func writeSocks5Address(buf *bytes.Buffer, address string) error {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return fmt.Errorf("socks5: invalid port %q", portString)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			buf.WriteByte(socks5AddressIPv4)
+			buf.Write(ip4)
+		} else {
+			buf.WriteByte(socks5AddressIPv6)
+			buf.Write(ip.To16())
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("socks5: host %q is too long", host)
+		}
+		buf.WriteByte(socks5AddressDomain)
+		buf.WriteByte(byte(len(host)))
+		buf.WriteString(host)
+	}
+
+	return binary.Write(buf, binary.BigEndian, uint16(port))
+}
+
+// readSocks5Address reads an address in the format of the reply and UDP headers. Domain names are
+// not resolved and reported with an unspecified IP.
+func readSocks5Address(r io.Reader) (*net.UDPAddr, error) {
+	addressType := make([]byte, 1)
+	if _, err := io.ReadFull(r, addressType); err != nil {
+		return nil, err
+	}
+
+	var ip net.IP
+	switch addressType[0] {
+	case socks5AddressIPv4:
+		ip = make(net.IP, net.IPv4len)
+	case socks5AddressIPv6:
+		ip = make(net.IP, net.IPv6len)
+	case socks5AddressDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(r, length); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(r, make([]byte, length[0])); err != nil {
+			return nil, err
+		}
+		ip = net.IPv4zero
+	default:
+		return nil, fmt.Errorf("socks5: unknown address type 0x%02x", addressType[0])
+	}
+
+	if addressType[0] != socks5AddressDomain {
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	var port uint16
+	if err := binary.Read(r, binary.BigEndian, &port); err != nil {
+		return nil, err
+	}
+
+	return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// socks5Conn is a connection through the proxy that reports the server as its remote address
+type socks5Conn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *socks5Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// socks5PacketConn relays datagrams to a single server through the UDP relay of the proxy
+type socks5PacketConn struct {
+	net.Conn
+	control net.Conn
+	remote  net.Addr
+	// header is the UDP request header addressed to remote
+	header []byte
+}
+
+func (c *socks5PacketConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *socks5PacketConn) Write(b []byte) (int, error) {
+	datagram := make([]byte, 0, len(c.header)+len(b))
+	datagram = append(datagram, c.header...)
+	datagram = append(datagram, b...)
+
+	if _, err := c.Conn.Write(datagram); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Read returns the payload of the next datagram, skipping fragmented datagrams and those that were
+// not sent by the server
+func (c *socks5PacketConn) Read(b []byte) (int, error) {
+	datagram := make([]byte, maxDatagramSize)
+	for {
+		n, err := c.Conn.Read(datagram)
+		if err != nil {
+			return 0, err
+		}
+
+		reader := bytes.NewReader(datagram[:n])
+		header := make([]byte, 3)
+		if _, err := io.ReadFull(reader, header); err != nil || header[2] != 0x00 {
+			continue
+		}
+
+		source, err := readSocks5Address(reader)
+		if err != nil || !c.sentByRemote(source) {
+			continue
+		}
+
+		if reader.Len() == 0 {
+			return 0, nil
+		}
+		return reader.Read(b)
+	}
+}
+
+// sentByRemote reports whether a datagram from source was sent by the server. The IP a host name
+// resolved to is only known to the proxy, so only the port is compared for those.
+func (c *socks5PacketConn) sentByRemote(source *net.UDPAddr) bool {
+	switch remote := c.remote.(type) {
+	case *net.UDPAddr:
+		return source.IP.Equal(remote.IP) && source.Port == remote.Port
+	case *socks5HostAddr:
+		return source.Port == remote.port
+	default:
+		return false
+	}
+}
+
+func (c *socks5PacketConn) Close() error {
+	c.control.Close()
+	return c.Conn.Close()
+}
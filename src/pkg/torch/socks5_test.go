@@ -0,0 +1,401 @@
+package torch
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// socks5Request is a command received by stubSOCKS5
+type socks5Request struct {
+	command     byte
+	addressType byte
+	address     string
+}
+
+// stubSOCKS5 is a SOCKS5 proxy whose CONNECT echoes everything back and whose UDP relay echoes datagrams
+// back from the address they were sent to
+type stubSOCKS5 struct {
+	addr     string
+	listener net.Listener
+	relay    net.PacketConn
+	requests chan socks5Request
+
+	// username and password are required if username is set
+	username string
+	password string
+	// reply is the REP code sent for requests
+	reply byte
+	// truncate closes the connection in the middle of the reply
+	truncate bool
+	// fragment makes the relay send a datagram with a non-zero FRAG before each echo
+	fragment bool
+}
+
+func newStubSOCKS5(t *testing.T, configure func(s *stubSOCKS5)) *stubSOCKS5 {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	relay, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		listener.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		relay.Close()
+	})
+
+	s := &stubSOCKS5{
+		addr:     listener.Addr().String(),
+		listener: listener,
+		relay:    relay,
+		requests: make(chan socks5Request, 10),
+	}
+	if configure != nil {
+		configure(s)
+	}
+
+	go s.serve()
+	go s.serveRelay()
+
+	return s
+}
+
+func (s *stubSOCKS5) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *stubSOCKS5) handle(conn net.Conn) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+
+	method := byte(socks5AuthNone)
+	if s.username != "" {
+		method = socks5AuthPassword
+	}
+	if bytes.IndexByte(methods, method) < 0 {
+		conn.Write([]byte{socks5Version, socks5AuthUnacceptable})
+		return
+	}
+	conn.Write([]byte{socks5Version, method})
+
+	if method == socks5AuthPassword {
+		username, password, err := readSocks5Credentials(conn)
+		if err != nil {
+			return
+		}
+		if username != s.username || password != s.password {
+			conn.Write([]byte{socks5PasswordVersion, 0x01})
+			return
+		}
+		conn.Write([]byte{socks5PasswordVersion, 0x00})
+	}
+
+	request := make([]byte, 3)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return
+	}
+	addressType, address, err := readSocks5Destination(conn)
+	if err != nil {
+		return
+	}
+	s.requests <- socks5Request{command: request[1], addressType: addressType, address: address}
+
+	switch {
+	case s.truncate:
+		conn.Write([]byte{socks5Version, 0x00, 0x00, socks5AddressIPv4, 127})
+	case s.reply != 0x00:
+		conn.Write([]byte{socks5Version, s.reply, 0x00, socks5AddressIPv4, 0, 0, 0, 0, 0, 0})
+	case request[1] == socks5CommandConnect:
+		conn.Write([]byte{socks5Version, 0x00, 0x00, socks5AddressIPv4, 0, 0, 0, 0, 0, 0})
+		io.Copy(conn, conn)
+	case request[1] == socks5CommandUDPAssociate:
+		relay := s.relay.LocalAddr().(*net.UDPAddr)
+		reply := append([]byte{socks5Version, 0x00, 0x00, socks5AddressIPv4}, relay.IP.To4()...)
+		conn.Write(binary.BigEndian.AppendUint16(reply, uint16(relay.Port)))
+		// The association lasts until the client closes the connection
+		io.Copy(io.Discard, conn)
+	}
+}
+
+// serveRelay echoes datagrams back from their destination, answering for host names from 192.0.2.1
+// like a proxy that resolved them
+func (s *stubSOCKS5) serveRelay() {
+	buf := make([]byte, 65535)
+	for {
+		n, client, err := s.relay.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		reader := bytes.NewReader(buf[:n])
+		header := make([]byte, 3)
+		if _, err := io.ReadFull(reader, header); err != nil || header[2] != 0x00 {
+			continue
+		}
+		addressType, address, err := readSocks5Destination(reader)
+		if err != nil {
+			continue
+		}
+		payload := buf[n-reader.Len() : n]
+
+		host, port, _ := net.SplitHostPort(address)
+		if addressType == socks5AddressDomain {
+			address = net.JoinHostPort("192.0.2.1", port)
+		}
+		source := &bytes.Buffer{}
+		if err := writeSocks5Address(source, address); err != nil {
+			continue
+		}
+		s.requests <- socks5Request{addressType: addressType, address: net.JoinHostPort(host, port)}
+
+		if s.fragment {
+			fragment := append([]byte{0x00, 0x00, 0x01}, source.Bytes()...)
+			s.relay.WriteTo(append(fragment, "fragment"...), client)
+		}
+		echo := append([]byte{0x00, 0x00, 0x00}, source.Bytes()...)
+		s.relay.WriteTo(append(echo, payload...), client)
+	}
+}
+
+func readSocks5Credentials(r io.Reader) (string, string, error) {
+	version := make([]byte, 1)
+	if _, err := io.ReadFull(r, version); err != nil {
+		return "", "", err
+	}
+	username, err := readSocks5String(r)
+	if err != nil {
+		return "", "", err
+	}
+	password, err := readSocks5String(r)
+	return username, password, err
+}
+
+func readSocks5String(r io.Reader) (string, error) {
+	length := make([]byte, 1)
+	if _, err := io.ReadFull(r, length); err != nil {
+		return "", err
+	}
+	value := make([]byte, length[0])
+	_, err := io.ReadFull(r, value)
+	return string(value), err
+}
+
+// readSocks5Destination reads an address of a request, keeping domain names unlike readSocks5Address
+func readSocks5Destination(r io.Reader) (byte, string, error) {
+	addressType := make([]byte, 1)
+	if _, err := io.ReadFull(r, addressType); err != nil {
+		return 0, "", err
+	}
+
+	var host string
+	switch addressType[0] {
+	case socks5AddressIPv4, socks5AddressIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if addressType[0] == socks5AddressIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return 0, "", err
+		}
+		host = ip.String()
+	default:
+		var err error
+		if host, err = readSocks5String(r); err != nil {
+			return 0, "", err
+		}
+	}
+
+	var port uint16
+	if err := binary.Read(r, binary.BigEndian, &port); err != nil {
+		return 0, "", err
+	}
+
+	return addressType[0], net.JoinHostPort(host, strconv.Itoa(int(port))), nil
+}
+
+func socks5TestContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestSOCKS5Connect(t *testing.T) {
+	tests := []struct {
+		address     string
+		addressType byte
+	}{
+		{"play.example.test:25565", socks5AddressDomain},
+		{"192.0.2.1:25565", socks5AddressIPv4},
+		{"[2001:db8::1]:25565", socks5AddressIPv6},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.address, func(t *testing.T) {
+			stub := newStubSOCKS5(t, nil)
+			dialer := &SOCKS5Dialer{Address: stub.addr}
+
+			conn, err := dialer.DialContext(socks5TestContext(t), "tcp", test.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			request := <-stub.requests
+			if request.command != socks5CommandConnect || request.addressType != test.addressType || request.address != test.address {
+				t.Errorf("request = %+v, want CONNECT to %s with address type 0x%02x", request, test.address, test.addressType)
+			}
+			if remote := conn.RemoteAddr().String(); remote != test.address {
+				t.Errorf("RemoteAddr() = %s, want %s", remote, test.address)
+			}
+
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			echo := make([]byte, 4)
+			if _, err := io.ReadFull(conn, echo); err != nil || string(echo) != "ping" {
+				t.Errorf("read %q, %v, want ping", echo, err)
+			}
+		})
+	}
+}
+
+func TestSOCKS5Authentication(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		err      string
+	}{
+		{"accepted", "steve", "diamond", ""},
+		{"wrong password", "steve", "dirt", "authentication failed"},
+		{"no credentials", "", "", "no acceptable authentication method"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			stub := newStubSOCKS5(t, func(s *stubSOCKS5) {
+				s.username, s.password = "steve", "diamond"
+			})
+			dialer := &SOCKS5Dialer{Address: stub.addr, Username: test.username, Password: test.password}
+
+			conn, err := dialer.DialContext(socks5TestContext(t), "tcp", "play.example.test:25565")
+			if err == nil {
+				conn.Close()
+			}
+
+			if test.err == "" && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+			if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+				t.Errorf("err = %v, want %s", err, test.err)
+			}
+		})
+	}
+}
+
+func TestSOCKS5ReplyError(t *testing.T) {
+	tests := []struct {
+		reply byte
+		err   string
+	}{
+		{0x05, "socks5: connection refused"},
+		{0x42, "socks5: request failed with reply 0x42"},
+	}
+
+	for _, test := range tests {
+		stub := newStubSOCKS5(t, func(s *stubSOCKS5) {
+			s.reply = test.reply
+		})
+		dialer := &SOCKS5Dialer{Address: stub.addr}
+
+		if _, err := dialer.DialContext(socks5TestContext(t), "tcp", "play.example.test:25565"); err == nil || err.Error() != test.err {
+			t.Errorf("reply 0x%02x: err = %v, want %s", test.reply, err, test.err)
+		}
+	}
+}
+
+func TestSOCKS5TruncatedReply(t *testing.T) {
+	stub := newStubSOCKS5(t, func(s *stubSOCKS5) {
+		s.truncate = true
+	})
+	dialer := &SOCKS5Dialer{Address: stub.addr}
+
+	if _, err := dialer.DialContext(socks5TestContext(t), "tcp", "play.example.test:25565"); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestSOCKS5UDPRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		fragment bool
+	}{
+		{"ip", "192.0.2.1:19132", false},
+		{"host name", "bedrock.example.test:19132", false},
+		{"fragment dropped", "192.0.2.1:19132", true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			stub := newStubSOCKS5(t, func(s *stubSOCKS5) {
+				s.fragment = test.fragment
+			})
+			dialer := &SOCKS5Dialer{Address: stub.addr}
+
+			conn, err := dialer.DialContext(socks5TestContext(t), "udp", test.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if request := <-stub.requests; request.command != socks5CommandUDPAssociate {
+				t.Errorf("request = %+v, want UDP ASSOCIATE", request)
+			}
+
+			conn.SetDeadline(time.Now().Add(2 * time.Second))
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			if datagram := <-stub.requests; datagram.address != test.address {
+				t.Errorf("datagram sent to %s, want %s", datagram.address, test.address)
+			}
+
+			buf := make([]byte, 64)
+			n, err := conn.Read(buf)
+			if err != nil || string(buf[:n]) != "ping" {
+				t.Errorf("read %q, %v, want ping", buf[:n], err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package torch
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"torch/src/structs"
+)
+
+//go:embed software_rules.json
+var defaultSoftwareRules []byte
+
+// softwareRules are loaded from software_rules.json unless replaced with SetSoftwareRules
+var softwareRules = mustParseSoftwareRules(defaultSoftwareRules)
+
+// softwareRule identifies a server software when every pattern in Match matches the fact of the same name.
+// Facts that are not present are matched as an empty string. A named group "version" in any pattern
+// is reported as the software version.
+type softwareRule struct {
+	Name       string            `json:"name"`
+	Family     string            `json:"family"`
+	Edition    string            `json:"edition"`
+	Match      map[string]string `json:"match"`
+	Confidence float64           `json:"confidence"`
+	IsProxy    bool              `json:"is_proxy"`
+
+	patterns map[string]*regexp.Regexp
+}
+
+// SetSoftwareRules replaces the software fingerprinting rules with the JSON array in data, in the
+// format of software_rules.json. It is not safe to call while servers are being looked up.
+func SetSoftwareRules(data []byte) error {
+	rules, err := parseSoftwareRules(data)
+	if err != nil {
+		return err
+	}
+
+	softwareRules = rules
+	return nil
+}
+
+func mustParseSoftwareRules(data []byte) []softwareRule {
+	rules, err := parseSoftwareRules(data)
+	if err != nil {
+		panic(fmt.Sprintf("software rules: %s", err))
+	}
+
+	return rules
+}
+
+func parseSoftwareRules(data []byte) ([]softwareRule, error) {
+	var rules []softwareRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	for i := range rules {
+		rules[i].patterns = make(map[string]*regexp.Regexp)
+		for fact, pattern := range rules[i].Match {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rules[i].Name, err)
+			}
+			rules[i].patterns[fact] = compiled
+		}
+	}
+
+	return rules, nil
+}
+
+// fingerprint returns the matching rule with the highest confidence for the given edition
+func fingerprint(edition string, facts map[string]string) *structs.Software {
+	var result *structs.Software
+
+	for _, rule := range softwareRules {
+		if rule.Edition != "" && rule.Edition != edition {
+			continue
+		}
+		if result != nil && rule.Confidence <= result.Confidence {
+			continue
+		}
+
+		version, ok := rule.match(facts)
+		if !ok {
+			continue
+		}
+
+		result = &structs.Software{
+			Name:       rule.Name,
+			Version:    version,
+			Family:     rule.Family,
+			Confidence: rule.Confidence,
+			IsProxy:    rule.IsProxy,
+		}
+	}
+
+	return result
+}
+
+func (r *softwareRule) match(facts map[string]string) (string, bool) {
+	version := ""
+
+	for fact, pattern := range r.patterns {
+		match := pattern.FindStringSubmatch(facts[fact])
+		if match == nil {
+			return "", false
+		}
+		if index := pattern.SubexpIndex("version"); index >= 0 && match[index] != "" {
+			version = match[index]
+		}
+	}
+
+	return version, true
+}
+
+// fingerprintJava matches the Java rules, protocolVersion is the version sent in the handshake which
+// some proxies echo back
+func fingerprintJava(status *structs.JavaStatus, protocolVersion int32) *structs.Software {
+	facts := map[string]string{
+		"protocol":      strconv.Itoa(status.Version.Protocol),
+		"protocol_echo": strconv.FormatBool(status.Version.Protocol == int(protocolVersion)),
+		"ping_version":  status.PingVersion,
+	}
+
+	if status.Version.Name != nil {
+		facts["version"] = status.Version.Name.Clean
+	}
+	if status.Description != nil {
+		facts["motd"] = status.Description.Clean
+	}
+	if status.ModInfo != nil {
+		facts["mod_type"] = status.ModInfo.Type
+
+		channels := make([]string, 0)
+		for _, channel := range status.ModInfo.Channels {
+			channels = append(channels, channel.Name)
+		}
+		facts["channels"] = strings.Join(channels, ",")
+	}
+
+	return fingerprint("java", facts)
+}
+
+// fingerprintBedrock uses the raw fields of the unconnected pong as some of them are merged in BedrockStatus
+func fingerprintBedrock(status *structs.BedrockStatus, fields []string) *structs.Software {
+	names := []string{"edition", "motd", "protocol", "version", "online", "max", "server_id", "sub_motd", "gamemode", "gamemode_id", "port_ipv4", "port_ipv6"}
+
+	facts := make(map[string]string)
+	for i, name := range names {
+		if i < len(fields) {
+			facts[name] = structs.Clean(strings.TrimSpace(fields[i]))
+		}
+	}
+
+	return fingerprint("bedrock", facts)
+}
@@ -0,0 +1,154 @@
+package torch
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+	"time"
+	"torch/src/structs"
+)
+
+// SRV lookup modes for Java servers
+const (
+	// SrvModeAuto looks up the SRV record only when the default port is used
+	SrvModeAuto = "auto"
+	// SrvModeForce looks up the SRV record regardless of the port
+	SrvModeForce = "force"
+	// SrvModeDisable never looks up the SRV record
+	SrvModeDisable = "disable"
+)
+
+// IsValidSrvMode reports whether mode is empty or one of the SRV lookup modes
+func IsValidSrvMode(mode string) bool {
+	switch mode {
+	case "", SrvModeAuto, SrvModeForce, SrvModeDisable:
+		return true
+	default:
+		return false
+	}
+}
+
+// LookupSRV looks up the _minecraft._tcp SRV record of host, returning nil if there is none. The
+// records are ordered by priority and weight, the first one is reported as Target and Port.
+func LookupSRV(ctx context.Context, host string, opts Options) (*structs.Srv, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	addrs, ttl, err := opts.resolver().LookupSRV(ctx, "minecraft", "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+
+	// A single record with a target of "." means the service is decidedly not available
+	if len(addrs) < 1 || (len(addrs) == 1 && addrs[0].Target == ".") {
+		return nil, nil
+	}
+
+	records := make([]structs.SrvTarget, 0)
+	for _, addr := range orderSrv(addrs) {
+		records = append(records, structs.SrvTarget{
+			Target:   strings.TrimSuffix(addr.Target, "."),
+			Port:     addr.Port,
+			Priority: addr.Priority,
+			Weight:   addr.Weight,
+		})
+	}
+
+	return &structs.Srv{
+		Target:     records[0].Target,
+		Port:       records[0].Port,
+		Records:    records,
+		ObtainedAt: time.Now(),
+		ExpiresAt:  time.Now().Add(clampTTL(ttl)),
+	}, nil
+}
+
+// srvRandom returns a random number in [0, n) for the weighted selection of SRV records
+var srvRandom = rand.Intn
+
+// orderSrv orders records by priority and, within the same priority, by a weighted random selection
+// as described in RFC 2782
+func orderSrv(addrs []*net.SRV) []*net.SRV {
+	sorted := make([]*net.SRV, len(addrs))
+	copy(sorted, addrs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	ordered := make([]*net.SRV, 0, len(sorted))
+	for start := 0; start < len(sorted); {
+		end := start
+		for end < len(sorted) && sorted[end].Priority == sorted[start].Priority {
+			end++
+		}
+		ordered = append(ordered, orderSrvByWeight(sorted[start:end])...)
+		start = end
+	}
+
+	return ordered
+}
+
+func orderSrvByWeight(group []*net.SRV) []*net.SRV {
+	// Records with a weight of 0 are placed first so they have a small chance of being selected
+	remaining := make([]*net.SRV, 0, len(group))
+	for _, addr := range group {
+		if addr.Weight == 0 {
+			remaining = append(remaining, addr)
+		}
+	}
+	for _, addr := range group {
+		if addr.Weight != 0 {
+			remaining = append(remaining, addr)
+		}
+	}
+
+	ordered := make([]*net.SRV, 0, len(group))
+	for len(remaining) > 0 {
+		total := 0
+		for _, addr := range remaining {
+			total += int(addr.Weight)
+		}
+
+		selected, sum, threshold := 0, 0, srvRandom(total+1)
+		for i, addr := range remaining {
+			sum += int(addr.Weight)
+			if sum >= threshold {
+				selected = i
+				break
+			}
+		}
+
+		ordered = append(ordered, remaining[selected])
+		remaining = append(remaining[:selected], remaining[selected+1:]...)
+	}
+
+	return ordered
+}
+
+// javaCandidates returns the addresses to try for a Java server in order of preference, along with
+// the SRV record they came from if one was used
+func javaCandidates(ctx context.Context, host string, port uint16, opts Options) ([]Target, *structs.Srv) {
+	mode := opts.SrvMode
+	if mode == SrvModeForce || ((mode == "" || mode == SrvModeAuto) && port == 25565) {
+		srv, _ := LookupSRV(ctx, host, opts)
+		if srv != nil {
+			candidates := make([]Target, 0)
+			for _, record := range srv.Records {
+				candidates = append(candidates, Target{record.Target, record.Port})
+			}
+			return candidates, srv
+		}
+	}
+
+	return []Target{{host, port}}, nil
+}
+
+// isDialError reports whether the connection could not be established, in which case the next
+// candidate may be tried
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,100 @@
+package torch
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+// withSrvRandom replaces the random source of orderSrv for the duration of the test
+func withSrvRandom(t *testing.T, random func(n int) int) {
+	original := srvRandom
+	srvRandom = random
+	t.Cleanup(func() { srvRandom = original })
+}
+
+func srvTargets(addrs []*net.SRV) []string {
+	targets := make([]string, len(addrs))
+	for i, addr := range addrs {
+		targets[i] = addr.Target
+	}
+	return targets
+}
+
+func TestOrderSrvGroupsByPriority(t *testing.T) {
+	withSrvRandom(t, rand.New(rand.NewSource(1)).Intn)
+
+	addrs := []*net.SRV{
+		{Target: "c", Priority: 30, Weight: 100},
+		{Target: "a1", Priority: 10, Weight: 1},
+		{Target: "b", Priority: 20, Weight: 0},
+		{Target: "a2", Priority: 10, Weight: 50},
+	}
+
+	for i := 0; i < 100; i++ {
+		ordered := orderSrv(addrs)
+		if len(ordered) != len(addrs) {
+			t.Fatalf("ordered %d records, want %d", len(ordered), len(addrs))
+		}
+
+		// Weights only order records within the same priority
+		targets := srvTargets(ordered)
+		if !((targets[0] == "a1" && targets[1] == "a2") || (targets[0] == "a2" && targets[1] == "a1")) || targets[2] != "b" || targets[3] != "c" {
+			t.Fatalf("ordered %v", targets)
+		}
+	}
+}
+
+func TestOrderSrvZeroWeights(t *testing.T) {
+	group := []*net.SRV{
+		{Target: "heavy", Weight: 10},
+		{Target: "zero1", Weight: 0},
+		{Target: "zero2", Weight: 0},
+	}
+
+	// Zero weights are placed first, so they are only selected when the random number is 0
+	withSrvRandom(t, func(n int) int { return 0 })
+	if targets := srvTargets(orderSrvByWeight(group)); targets[0] != "zero1" || targets[1] != "zero2" || targets[2] != "heavy" {
+		t.Errorf("ordered %v with a random number of 0, want [zero1 zero2 heavy]", targets)
+	}
+
+	withSrvRandom(t, func(n int) int { return n - 1 })
+	if targets := srvTargets(orderSrvByWeight(group)); targets[0] != "heavy" || targets[1] != "zero1" || targets[2] != "zero2" {
+		t.Errorf("ordered %v with the largest random number, want [heavy zero1 zero2]", targets)
+	}
+
+	// Only zero weights keep their order
+	zeros := []*net.SRV{{Target: "zero1"}, {Target: "zero2"}, {Target: "zero3"}}
+	if targets := srvTargets(orderSrvByWeight(zeros)); targets[0] != "zero1" || targets[1] != "zero2" || targets[2] != "zero3" {
+		t.Errorf("ordered %v, want [zero1 zero2 zero3]", targets)
+	}
+}
+
+func TestOrderSrvWeightDistribution(t *testing.T) {
+	withSrvRandom(t, rand.New(rand.NewSource(1)).Intn)
+
+	group := []*net.SRV{
+		{Target: "light", Weight: 10},
+		{Target: "heavy", Weight: 30},
+		{Target: "zero", Weight: 0},
+	}
+
+	const runs = 20000
+	first := make(map[string]int)
+	for i := 0; i < runs; i++ {
+		first[orderSrvByWeight(group)[0].Target]++
+	}
+
+	// RFC 2782 picks a number in [0, 40], zero is first for 0, light for 1-10 and heavy for 11-40
+	want := map[string]float64{
+		"zero":  1.0 / 41,
+		"light": 10.0 / 41,
+		"heavy": 30.0 / 41,
+	}
+	for target, probability := range want {
+		if share := float64(first[target]) / runs; math.Abs(share-probability) > 0.015 {
+			t.Errorf("%s was first in %.3f of the runs, want %.3f", target, share, probability)
+		}
+	}
+}
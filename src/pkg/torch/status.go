@@ -0,0 +1,78 @@
+package torch
+
+import (
+	"regexp"
+	"strings"
+
+	"torch/src/structs"
+)
+
+var versionNumberRegex = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+
+// crossplaySignals are the weights of each signal that a Java and Bedrock server belong to the same network
+var crossplaySignals = map[string]float64{
+	"motd":     0.4,
+	"players":  0.3,
+	"software": 0.3,
+	"version":  0.2,
+}
+
+// crossplayThreshold is the confidence above which both editions are reported as the same network
+const crossplayThreshold = 0.5
+
+// crossplayIdentitySignals are the signals of which at least one must match for both editions to be
+// reported as the same network, as the others are common among unrelated servers (e.g. 0/20 players
+// on the latest version)
+var crossplayIdentitySignals = map[string]bool{
+	"motd":     true,
+	"software": true,
+}
+
+// CorrelateCrossplay estimates whether a Java and a Bedrock server found on the same host belong to
+// the same network
+func CorrelateCrossplay(java *structs.JavaStatus, bedrock *structs.BedrockStatus) *structs.Crossplay {
+	crossplay := &structs.Crossplay{
+		Signals: make([]string, 0),
+	}
+
+	if java.Description != nil && bedrock.MOTD != nil {
+		javaMotd, bedrockMotd := normalizeMotd(java.Description.Clean), normalizeMotd(bedrock.MOTD.Clean)
+		if javaMotd != "" && javaMotd == bedrockMotd {
+			crossplay.Signals = append(crossplay.Signals, "motd")
+		}
+	}
+
+	if java.Players.Online == bedrock.Players.Online && java.Players.Max == bedrock.Players.Max {
+		crossplay.Signals = append(crossplay.Signals, "players")
+	}
+
+	if bedrock.Software != nil && bedrock.Software.Family == "geyser" {
+		crossplay.Signals = append(crossplay.Signals, "software")
+	}
+
+	if java.Version.Name != nil && bedrock.Version.Name != nil {
+		javaVersion := versionNumberRegex.FindString(java.Version.Name.Clean)
+		bedrockVersion := versionNumberRegex.FindString(bedrock.Version.Name.Clean)
+		if javaVersion != "" && javaVersion == bedrockVersion {
+			crossplay.Signals = append(crossplay.Signals, "version")
+		}
+	}
+
+	identified := false
+	for _, signal := range crossplay.Signals {
+		crossplay.Confidence += crossplaySignals[signal]
+		identified = identified || crossplayIdentitySignals[signal]
+	}
+	if crossplay.Confidence > 1 {
+		crossplay.Confidence = 1
+	}
+	crossplay.SameNetwork = identified && crossplay.Confidence >= crossplayThreshold
+
+	return crossplay
+}
+
+// normalizeMotd returns the first line of a MOTD in lower case with collapsed whitespace
+func normalizeMotd(motd string) string {
+	line, _, _ := strings.Cut(motd, "\n")
+	return strings.ToLower(strings.Join(strings.Fields(line), " "))
+}
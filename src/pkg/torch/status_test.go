@@ -0,0 +1,48 @@
+package torch
+
+import (
+	"testing"
+
+	"torch/src/structs"
+)
+
+func crossplayServers(javaMotd string, bedrockMotd string, online int, max int, version string) (*structs.JavaStatus, *structs.BedrockStatus) {
+	java := &structs.JavaStatus{
+		Description: structs.Parse(javaMotd),
+		Players:     structs.Players{Online: online, Max: max},
+		Version:     structs.Version{Name: structs.Parse("Paper " + version)},
+	}
+	bedrock := &structs.BedrockStatus{
+		MOTD:    structs.Parse(bedrockMotd),
+		Players: structs.Players{Online: online, Max: max},
+		Version: structs.Version{Name: structs.Parse(version)},
+	}
+	return java, bedrock
+}
+
+func TestCorrelateCrossplay(t *testing.T) {
+	tests := []struct {
+		name        string
+		javaMotd    string
+		bedrockMotd string
+		geyser      bool
+		sameNetwork bool
+	}{
+		{"same motd", "A Minecraft Network", "a  minecraft network", false, true},
+		{"geyser", "Java lobby", "Bedrock lobby", true, true},
+		// Players and version alone reach the threshold, but empty servers on the same version are common
+		{"unrelated empty servers", "Survival", "Creative", false, false},
+	}
+
+	for _, test := range tests {
+		java, bedrock := crossplayServers(test.javaMotd, test.bedrockMotd, 0, 20, "1.20.4")
+		if test.geyser {
+			bedrock.Software = &structs.Software{Name: "Geyser", Family: "geyser"}
+		}
+
+		crossplay := CorrelateCrossplay(java, bedrock)
+		if crossplay.SameNetwork != test.sameNetwork {
+			t.Errorf("%s: same network = %t with signals %v, want %t", test.name, crossplay.SameNetwork, crossplay.Signals, test.sameNetwork)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package torch
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// hostProfile converts hostnames to ASCII for lookups. Underscores are allowed as they are common in
+// the hostnames of DNS records, which StrictDomainName rejects.
+var hostProfile = idna.New(idna.MapForLookup(), idna.VerifyDNSLength(true), idna.StrictDomainName(false))
+
+// Target is a normalized server address
+type Target struct {
+	Host string
+	Port uint16
+}
+
+// String returns the canonical host:port of the target, IPv6 addresses are bracketed
+func (t Target) String() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(int(t.Port)))
+}
+
+// ParseTarget parses host, host:port, [ipv6]:port or a bare IPv6 address into a normalized target,
+// using defaultPort when no port is given
+func ParseTarget(raw string, defaultPort uint16) (Target, error) {
+	raw = strings.TrimSpace(raw)
+	host, portString, hasPort := raw, "", false
+
+	switch {
+	case strings.HasPrefix(raw, "["):
+		end := strings.Index(raw, "]")
+		if end < 0 {
+			return Target{}, errors.New("missing closing bracket in IPv6 address")
+		}
+		host = raw[1:end]
+		rest := raw[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return Target{}, fmt.Errorf("unexpected %q after IPv6 address", rest)
+			}
+			portString, hasPort = rest[1:], true
+		}
+		if addr, err := netip.ParseAddr(host); err != nil || !addr.Is6() {
+			return Target{}, fmt.Errorf("invalid IPv6 address %q", host)
+		}
+	// More than one colon without brackets can only be a bare IPv6 address, which has no port
+	case strings.Count(raw, ":") == 1:
+		host, portString, hasPort = strings.Cut(raw, ":")
+	}
+
+	port := defaultPort
+	if hasPort {
+		p, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil || p == 0 {
+			return Target{}, fmt.Errorf("invalid port %q, must be between 1 and 65535", portString)
+		}
+		port = uint16(p)
+	}
+
+	host, err := ParseHost(host)
+	if err != nil {
+		return Target{}, err
+	}
+
+	return Target{host, port}, nil
+}
+
+// ParseHost normalizes an IP address or hostname. IPv4-mapped IPv6 addresses are converted to IPv4,
+// IPv6 zones are kept. Hostnames are lower-cased, converted to punycode and stripped of their
+// trailing dot.
+func ParseHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if host == "" {
+		return "", errors.New("missing host")
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String(), nil
+	}
+
+	ascii, err := hostProfile.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("invalid host %q: %s", host, err)
+	}
+
+	// Without StrictDomainName anything but letters, digits, hyphens and underscores has to be rejected here
+	if i := strings.IndexFunc(ascii, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.')
+	}); i >= 0 {
+		return "", fmt.Errorf("invalid host %q: invalid character %q", host, ascii[i])
+	}
+
+	if len(ascii) > 253 {
+		return "", fmt.Errorf("invalid host %q: longer than 253 characters", host)
+	}
+
+	return ascii, nil
+}
@@ -0,0 +1,73 @@
+package torch
+
+import "testing"
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Target
+		wantErr bool
+	}{
+		{raw: "example.com", want: Target{"example.com", 25565}},
+		{raw: "example.com:25566", want: Target{"example.com", 25566}},
+		{raw: "Play.Example.COM.", want: Target{"play.example.com", 25565}},
+		{raw: "play.example.com.:19132", want: Target{"play.example.com", 19132}},
+		{raw: "bücher.example", want: Target{"xn--bcher-kva.example", 25565}},
+		{raw: "under_score.example.com", want: Target{"under_score.example.com", 25565}},
+		{raw: "127.0.0.1:25565", want: Target{"127.0.0.1", 25565}},
+		{raw: "[2001:db8::1]:25566", want: Target{"2001:db8::1", 25566}},
+		{raw: "[2001:DB8::1]", want: Target{"2001:db8::1", 25565}},
+		{raw: "2001:db8::1", want: Target{"2001:db8::1", 25565}},
+		{raw: "::ffff:192.0.2.1", want: Target{"192.0.2.1", 25565}},
+		{raw: "[::ffff:192.0.2.1]:25566", want: Target{"192.0.2.1", 25566}},
+		{raw: "fe80::1%eth0", want: Target{"fe80::1%eth0", 25565}},
+		{raw: "[fe80::1%eth0]:25566", want: Target{"fe80::1%eth0", 25566}},
+
+		{raw: "example.com:0", wantErr: true},
+		{raw: "example.com:65536", wantErr: true},
+		{raw: "example.com:", wantErr: true},
+		{raw: "example.com:+80", wantErr: true},
+		{raw: "example.com:http", wantErr: true},
+		{raw: "[2001:db8::1", wantErr: true},
+		{raw: "[2001:db8::1]25565", wantErr: true},
+		{raw: "[192.0.2.1]:25565", wantErr: true},
+		{raw: "", wantErr: true},
+		{raw: ".", wantErr: true},
+		{raw: "..", wantErr: true},
+		{raw: "a..b", wantErr: true},
+		{raw: ".example.com", wantErr: true},
+		{raw: "exa mple.com", wantErr: true},
+		{raw: "example.com/path", wantErr: true},
+		{raw: "a23456789012345678901234567890123456789012345678901234567890abcd.com", wantErr: true},
+	}
+
+	for _, test := range tests {
+		target, err := ParseTarget(test.raw, 25565)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseTarget(%q) = %v, want an error", test.raw, target)
+			}
+			continue
+		}
+		if err != nil || target != test.want {
+			t.Errorf("ParseTarget(%q) = %v, %v, want %v", test.raw, target, err, test.want)
+		}
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{Target{"example.com", 25565}, "example.com:25565"},
+		{Target{"2001:db8::1", 19132}, "[2001:db8::1]:19132"},
+		{Target{"fe80::1%eth0", 25565}, "[fe80::1%eth0]:25565"},
+	}
+
+	for _, test := range tests {
+		if s := test.target.String(); s != test.want {
+			t.Errorf("%#v.String() = %q, want %q", test.target, s, test.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Package torch looks up the status of Minecraft Java and Bedrock Edition servers, along with the
+// DNS records used to reach them. It is used by the HTTP server but has no dependency on it.
+package torch
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout bounds each phase of a lookup (DNS, dial and every read) when Options.Timeout is not set
+	DefaultTimeout = 2 * time.Second
+	// DefaultProtocolVersion is sent in status handshakes, servers answer regardless of their own version
+	DefaultProtocolVersion = 47
+	// DefaultRetryBackoff is the delay before the first retry when Options.RetryBackoff is not set
+	DefaultRetryBackoff = 250 * time.Millisecond
+	// DefaultRetransmitInterval is how often Bedrock pings are resent when Options.RetransmitInterval is not set
+	DefaultRetransmitInterval = 500 * time.Millisecond
+)
+
+// Dialer opens the TCP and UDP connections to servers, *net.Dialer implements it
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Options controls how servers are looked up, the zero value uses the defaults
+type Options struct {
+	// Timeout bounds each phase of a lookup, DefaultTimeout if 0
+	Timeout time.Duration
+	// ConnectTimeout bounds dialing each address, Timeout if 0
+	ConnectTimeout time.Duration
+	// ReadTimeout bounds the exchange with a server once connected, Timeout if 0
+	ReadTimeout time.Duration
+	// Retries is how many times a failed FetchJava or FetchBedrock is retried, none if 0. Java servers
+	// that accepted the connection but timed out are not retried.
+	Retries int
+	// RetryBackoff is the delay before the first retry and doubles for every further one,
+	// DefaultRetryBackoff if 0
+	RetryBackoff time.Duration
+	// PingSamples is how many pings measure the latency, 1 if 0. Java pings are sent on the status
+	// connection, which servers may close after the first pong, leaving fewer samples.
+	PingSamples int
+	// RetransmitInterval is how often a Bedrock ping is resent while no pong arrived, within
+	// ReadTimeout. DefaultRetransmitInterval if 0.
+	RetransmitInterval time.Duration
+	// SrvMode is one of SrvModeAuto, SrvModeForce or SrvModeDisable, empty means SrvModeAuto
+	SrvMode string
+	// ProtocolVersion is sent in Java handshakes. For status pings it defaults to DefaultProtocolVersion,
+	// login checks use the version reported by the server if it is 0.
+	ProtocolVersion int
+	// ForgeMarker is one of fml, fml2 or fml3, or empty for a vanilla Java handshake
+	ForgeMarker string
+	// Dialer opens connections to servers, a net.Dialer if nil. DNS is sent through it only if the
+	// Resolver was created with it.
+	Dialer Dialer
+	// Resolver looks up SRV and address records, the system resolver if nil. Create it with the same
+	// dialer through NewResolver for DNS to go through Dialer as well.
+	Resolver Resolver
+	// ProxyProtocol is ProxyProtocolV1 or ProxyProtocolV2 to send a PROXY header before the Java
+	// handshake, for servers behind proxies that require it, or empty to send none
+	ProxyProtocol string
+	// ProxySource is the client address announced in the PROXY header, the local address if nil
+	ProxySource *net.TCPAddr
+}
+
+var (
+	defaultResolver     Resolver
+	defaultResolverOnce sync.Once
+)
+
+func (o Options) timeout() time.Duration {
+	if o.Timeout > 0 {
+		return o.Timeout
+	}
+	return DefaultTimeout
+}
+
+func (o Options) connectTimeout() time.Duration {
+	if o.ConnectTimeout > 0 {
+		return o.ConnectTimeout
+	}
+	return o.timeout()
+}
+
+func (o Options) readTimeout() time.Duration {
+	if o.ReadTimeout > 0 {
+		return o.ReadTimeout
+	}
+	return o.timeout()
+}
+
+func (o Options) retryBackoff() time.Duration {
+	if o.RetryBackoff > 0 {
+		return o.RetryBackoff
+	}
+	return DefaultRetryBackoff
+}
+
+func (o Options) retransmitInterval() time.Duration {
+	if o.RetransmitInterval > 0 {
+		return o.RetransmitInterval
+	}
+	return DefaultRetransmitInterval
+}
+
+func (o Options) pingSamples() int {
+	if o.PingSamples > 0 {
+		return o.PingSamples
+	}
+	return 1
+}
+
+func (o Options) protocolVersion() int32 {
+	if o.ProtocolVersion > 0 {
+		return int32(o.ProtocolVersion)
+	}
+	return DefaultProtocolVersion
+}
+
+func (o Options) resolver() Resolver {
+	if o.Resolver != nil {
+		return o.Resolver
+	}
+	defaultResolverOnce.Do(func() {
+		defaultResolver = NewResolver("", "", nil)
+	})
+	return defaultResolver
+}
+
+// dial connects to the address with the configured dialer, giving up after the connect timeout
+func (o Options) dial(ctx context.Context, network string, address string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, o.connectTimeout())
+	defer cancel()
+
+	if o.Dialer != nil {
+		return o.Dialer.DialContext(ctx, network, address)
+	}
+
+	dialer := net.Dialer{}
+	return dialer.DialContext(ctx, network, address)
+}
+
+// deadline returns the deadline for the next phase of a connection, which is the read timeout from
+// now unless ctx expires earlier
+func (o Options) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(o.readTimeout())
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
@@ -0,0 +1,231 @@
+package protocol
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"torch/src/utils"
+)
+
+// readerPool holds the buffered readers of released connections, so polling many servers doesn't
+// allocate a new buffer per connection
+var readerPool = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewReaderSize(nil, readerSize)
+	},
+}
+
+// readerSize fits the handshake replies and typical status responses in a single read
+const readerSize = 4096
+
+var errReleased = errors.New("protocol: connection already released")
+
+// Conn reads and writes packets for a single connection, tracking its state and compression.
+// Call Release once the connection is no longer used.
+type Conn struct {
+	w        io.Writer
+	reader   *bufio.Reader
+	registry Registry
+
+	// The buffers are reused between packets, decoded packets copy the data they keep
+	frame  []byte
+	data   []byte
+	out    bytes.Buffer
+	body   bytes.Buffer
+	packet bytes.Reader
+
+	// State determines which packets are expected when reading
+	State State
+	// Protocol is the protocol version passed to packets when encoding and decoding
+	Protocol int32
+
+	// threshold is the minimum size of a compressed packet, a negative value disables compression
+	threshold int32
+}
+
+func NewConn(rw io.ReadWriter, protocol int32) *Conn {
+	reader := readerPool.Get().(*bufio.Reader)
+	reader.Reset(rw)
+
+	return &Conn{
+		w:         rw,
+		reader:    reader,
+		registry:  Clientbound,
+		State:     StateHandshaking,
+		Protocol:  protocol,
+		threshold: -1,
+	}
+}
+
+// Release returns the buffered reader to the pool, the connection must not be used afterwards
+func (c *Conn) Release() {
+	if c.reader == nil {
+		return
+	}
+	c.reader.Reset(nil)
+	readerPool.Put(c.reader)
+	c.reader = nil
+}
+
+// SetCompression enables compression for packets of at least threshold bytes, a negative value disables it
+func (c *Conn) SetCompression(threshold int32) {
+	c.threshold = threshold
+}
+
+// WritePacket encodes the packet and writes it with a single Write call
+func (c *Conn) WritePacket(packet Packet) error {
+	c.body.Reset()
+	c.out.Reset()
+
+	if _, err := utils.WriteVarInt(packet.ID(), &c.body); err != nil {
+		return err
+	}
+
+	if err := packet.Encode(&c.body, c.Protocol); err != nil {
+		return err
+	}
+
+	if c.threshold >= 0 {
+		if err := c.compress(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := utils.WriteVarInt(int32(c.body.Len()), &c.out); err != nil {
+		return err
+	}
+	if _, err := c.body.WriteTo(&c.out); err != nil {
+		return err
+	}
+
+	_, err := c.w.Write(c.out.Bytes())
+	return err
+}
+
+// compress replaces the body with its data length and the compressed data
+func (c *Conn) compress() error {
+	compressed := &bytes.Buffer{}
+
+	// Packets below the threshold are sent with a data length of 0 to mark them as uncompressed
+	if int32(c.body.Len()) < c.threshold {
+		if _, err := utils.WriteVarInt(0, compressed); err != nil {
+			return err
+		}
+		if _, err := c.body.WriteTo(compressed); err != nil {
+			return err
+		}
+	} else {
+		if _, err := utils.WriteVarInt(int32(c.body.Len()), compressed); err != nil {
+			return err
+		}
+		writer := zlib.NewWriter(compressed)
+		if _, err := c.body.WriteTo(writer); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+	}
+
+	c.body.Reset()
+	_, err := compressed.WriteTo(&c.body)
+	return err
+}
+
+// Peek returns the next n bytes without consuming them
+func (c *Conn) Peek(n int) ([]byte, error) {
+	if c.reader == nil {
+		return nil, errReleased
+	}
+	return c.reader.Peek(n)
+}
+
+// ReadPacket reads the next packet and decodes it using the packets registered for the current state.
+// Unregistered packet IDs are returned as *UnknownPacket. Reading a SetCompression packet enables
+// compression for the following packets.
+func (c *Conn) ReadPacket() (Packet, error) {
+	data, err := c.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	packetId, _, err := utils.ReadVarInt(data)
+	if err != nil {
+		return nil, err
+	}
+
+	packet := c.registry.New(c.State, packetId)
+	if packet == nil {
+		packet = &UnknownPacket{PacketID: packetId}
+	}
+
+	if err := packet.Decode(data, c.Protocol); err != nil {
+		return nil, fmt.Errorf("%s packet 0x%02x: %w", c.State, packetId, err)
+	}
+
+	if setCompression, ok := packet.(*SetCompression); ok {
+		c.SetCompression(setCompression.Threshold)
+	}
+
+	return packet, nil
+}
+
+// readFrame reads a length-prefixed packet and decompresses it when compression is enabled. The
+// returned reader is only valid until the next call.
+func (c *Conn) readFrame() (*bytes.Reader, error) {
+	if c.reader == nil {
+		return nil, errReleased
+	}
+
+	frame, err := utils.ReadPacketBuffer(c.reader, c.frame)
+	if err != nil {
+		return nil, err
+	}
+	c.frame = frame
+	c.packet.Reset(frame)
+
+	if c.threshold < 0 {
+		return &c.packet, nil
+	}
+
+	dataLength, _, err := utils.ReadVarInt(&c.packet)
+	if err != nil {
+		return nil, err
+	}
+
+	if dataLength == 0 {
+		return &c.packet, nil
+	}
+
+	if dataLength < 0 || dataLength > utils.MaxPacketSize {
+		return nil, fmt.Errorf("packet: uncompressed length %d out of range (maximum %d)", dataLength, utils.MaxPacketSize)
+	}
+
+	reader, err := zlib.NewReader(&c.packet)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	if cap(c.data) < int(dataLength) {
+		c.data = make([]byte, dataLength)
+	}
+	c.data = c.data[:dataLength]
+	if _, err := io.ReadFull(reader, c.data); err != nil {
+		return nil, err
+	}
+
+	// Packets that decompress to more data than announced are rejected
+	var extra [1]byte
+	if n, _ := reader.Read(extra[:]); n > 0 {
+		return nil, fmt.Errorf("packet: uncompressed length exceeds announced %d", dataLength)
+	}
+
+	c.packet.Reset(c.data)
+	return &c.packet, nil
+}
@@ -0,0 +1,320 @@
+package protocol
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"torch/src/utils"
+)
+
+// readStatusResponseUnbuffered reads a status response the way it was read before Conn, straight from
+// the connection with a Read call per VarInt byte, kept to benchmark against
+func readStatusResponseUnbuffered(r io.Reader) ([]byte, error) {
+	readVarInt := func() (int32, error) {
+		var numRead int
+		var result int32
+		for {
+			data := make([]byte, 1)
+			if _, err := r.Read(data); err != nil {
+				return 0, err
+			}
+			result |= int32(data[0]&0b01111111) << (7 * numRead)
+			numRead++
+			if numRead > 5 {
+				return 0, fmt.Errorf("varint: attempted to read an oversized varint")
+			}
+			if data[0]&0b10000000 == 0 {
+				return result, nil
+			}
+		}
+	}
+
+	if _, err := readVarInt(); err != nil {
+		return nil, err
+	}
+
+	packetId, err := readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	if packetId != 0x00 {
+		return nil, fmt.Errorf("unexpected packet ID (expected 0x00, got 0x%02x)", packetId)
+	}
+
+	length, err := readVarInt()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// statusResponseFrame returns a status response packet with a typical amount of players and description
+func statusResponseFrame(b *testing.B) ([]byte, []byte) {
+	players := make([]map[string]string, 0)
+	for i := 0; i < 12; i++ {
+		players = append(players, map[string]string{
+			"name": fmt.Sprintf("player%d", i),
+			"id":   "069a79f4-44e9-4726-a5be-fca90e38aaf5",
+		})
+	}
+	status, err := json.Marshal(map[string]interface{}{
+		"version":     map[string]interface{}{"name": "Paper 1.20.4", "protocol": 765},
+		"players":     map[string]interface{}{"max": 100, "online": 12, "sample": players},
+		"description": map[string]interface{}{"text": strings.Repeat("A Minecraft Server ", 8)},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	frame := &bytes.Buffer{}
+	conn := NewConn(struct {
+		io.Reader
+		io.Writer
+	}{nil, frame}, 765)
+	defer conn.Release()
+	if err := conn.WritePacket(&StatusResponse{JSON: status}); err != nil {
+		b.Fatal(err)
+	}
+
+	return frame.Bytes(), status
+}
+
+// loopback returns both ends of a TCP connection, the server end writing frame count times
+func loopback(b *testing.B, frame []byte, count int) net.Conn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		server, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer server.Close()
+
+		for i := 0; i < count; i++ {
+			if _, err := server.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func BenchmarkReadStatusResponseUnbuffered(b *testing.B) {
+	frame, status := statusResponseFrame(b)
+	conn := loopback(b, frame, b.N)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		data, err := readStatusResponseUnbuffered(conn)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(data) != len(status) {
+			b.Fatalf("read %d bytes, want %d", len(data), len(status))
+		}
+	}
+}
+
+func BenchmarkReadStatusResponseConn(b *testing.B) {
+	frame, status := statusResponseFrame(b)
+	packetConn := NewConn(loopback(b, frame, b.N), 765)
+	defer packetConn.Release()
+	packetConn.State = StateStatus
+	b.ReportAllocs()
+	b.SetBytes(int64(len(frame)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		packet, err := packetConn.ReadPacket()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if response, ok := packet.(*StatusResponse); !ok || len(response.JSON) != len(status) {
+			b.Fatalf("read %#v", packet)
+		}
+	}
+}
+
+func TestRegistryNew(t *testing.T) {
+	if packet, ok := Clientbound.New(StateStatus, 0x00).(*StatusResponse); !ok {
+		t.Errorf("status packet 0x00 = %#v, want *StatusResponse", packet)
+	}
+	if packet, ok := Clientbound.New(StateLogin, 0x03).(*SetCompression); !ok {
+		t.Errorf("login packet 0x03 = %#v, want *SetCompression", packet)
+	}
+
+	// The same ID is a different packet in another state, and unregistered in the handshaking state
+	if packet := Clientbound.New(StateStatus, 0x03); packet != nil {
+		t.Errorf("status packet 0x03 = %#v, want nil", packet)
+	}
+	if packet := Clientbound.New(StateHandshaking, 0x00); packet != nil {
+		t.Errorf("handshaking packet 0x00 = %#v, want nil", packet)
+	}
+}
+
+func TestReadPacketUnknown(t *testing.T) {
+	buf := &bytes.Buffer{}
+	conn := NewConn(buf, 765)
+	defer conn.Release()
+	conn.State = StateStatus
+
+	if err := conn.WritePacket(&UnknownPacket{PacketID: 0x42, Data: []byte{0x01, 0x02, 0x03}}); err != nil {
+		t.Fatal(err)
+	}
+
+	packet, err := conn.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, ok := packet.(*UnknownPacket)
+	if !ok || unknown.PacketID != 0x42 || !bytes.Equal(unknown.Data, []byte{0x01, 0x02, 0x03}) {
+		t.Errorf("read %#v, want unknown packet 0x42 with its data", packet)
+	}
+}
+
+func TestCompressionThreshold(t *testing.T) {
+	const threshold = 64
+
+	tests := []struct {
+		name       string
+		json       []byte
+		compressed bool
+	}{
+		{"below", []byte(`{"description":"small"}`), false},
+		{"above", []byte(`{"description":"` + strings.Repeat("large ", 100) + `"}`), true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			conn := NewConn(buf, 765)
+			defer conn.Release()
+			conn.State = StateStatus
+			conn.SetCompression(threshold)
+
+			if err := conn.WritePacket(&StatusResponse{JSON: test.json}); err != nil {
+				t.Fatal(err)
+			}
+
+			// The data length after the packet length is 0 for packets sent uncompressed
+			frame := bytes.NewReader(buf.Bytes())
+			if _, _, err := utils.ReadVarInt(frame); err != nil {
+				t.Fatal(err)
+			}
+			dataLength, _, err := utils.ReadVarInt(frame)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if compressed := dataLength != 0; compressed != test.compressed {
+				t.Errorf("data length %d, compressed = %v, want %v", dataLength, compressed, test.compressed)
+			}
+
+			packet, err := conn.ReadPacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if response, ok := packet.(*StatusResponse); !ok || !bytes.Equal(response.JSON, test.json) {
+				t.Errorf("read %#v, want the written status response", packet)
+			}
+		})
+	}
+}
+
+func TestReadPacketEnablesCompression(t *testing.T) {
+	buf := &bytes.Buffer{}
+	server := NewConn(buf, 765)
+	defer server.Release()
+	if err := server.WritePacket(&SetCompression{Threshold: 0}); err != nil {
+		t.Fatal(err)
+	}
+	server.SetCompression(0)
+	if err := server.WritePacket(&LoginSuccess{Username: "Notch"}); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewConn(buf, 765)
+	defer client.Release()
+	client.State = StateLogin
+	for _, want := range []Packet{&SetCompression{}, &LoginSuccess{}} {
+		packet, err := client.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if packet.ID() != want.ID() {
+			t.Errorf("read packet 0x%02x, want 0x%02x", packet.ID(), want.ID())
+		}
+	}
+}
+
+// compressedFrame returns a frame announcing dataLength bytes of uncompressed data, holding data compressed
+func compressedFrame(t *testing.T, dataLength int32, data []byte) []byte {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	utils.WriteVarInt(dataLength, body)
+	writer := zlib.NewWriter(body)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := &bytes.Buffer{}
+	utils.WriteVarInt(int32(body.Len()), frame)
+	body.WriteTo(frame)
+	return frame.Bytes()
+}
+
+func TestReadFrameRejectsZlibBomb(t *testing.T) {
+	// 16 MiB of zeros compress to about 16 KiB, well within the packet size
+	bomb := make([]byte, 16*1024*1024)
+
+	tests := []struct {
+		name       string
+		dataLength int32
+	}{
+		{"announced small", 16},
+		{"announced maximum", utils.MaxPacketSize},
+		{"announced above maximum", utils.MaxPacketSize + 1},
+		{"announced negative", -1},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conn := NewConn(bytes.NewBuffer(compressedFrame(t, test.dataLength, bomb)), 765)
+			defer conn.Release()
+			conn.State = StateStatus
+			conn.SetCompression(0)
+
+			if packet, err := conn.ReadPacket(); err == nil {
+				t.Errorf("read %T from a packet decompressing to %d bytes", packet, len(bomb))
+			}
+		})
+	}
+}
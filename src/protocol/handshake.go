@@ -0,0 +1,51 @@
+package protocol
+
+import (
+	"bytes"
+
+	"torch/src/utils"
+)
+
+// Handshake is the first packet of a connection and switches it to NextState
+type Handshake struct {
+	ProtocolVersion int32
+	ServerAddress   string
+	ServerPort      uint16
+	NextState       State
+}
+
+func (p *Handshake) ID() int32 {
+	return 0x00
+}
+
+func (p *Handshake) Encode(w *bytes.Buffer, protocol int32) error {
+	if _, err := utils.WriteVarInt(p.ProtocolVersion, w); err != nil {
+		return err
+	}
+	if err := utils.WriteString(p.ServerAddress, w); err != nil {
+		return err
+	}
+	if err := WriteUnsignedShort(p.ServerPort, w); err != nil {
+		return err
+	}
+	_, err := utils.WriteVarInt(int32(p.NextState), w)
+	return err
+}
+
+func (p *Handshake) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	if p.ProtocolVersion, _, err = utils.ReadVarInt(r); err != nil {
+		return err
+	}
+	address, err := utils.ReadString(r)
+	if err != nil {
+		return err
+	}
+	p.ServerAddress = string(address)
+	if p.ServerPort, err = ReadUnsignedShort(r); err != nil {
+		return err
+	}
+	nextState, _, err := utils.ReadVarInt(r)
+	p.NextState = State(nextState)
+	return err
+}
@@ -0,0 +1,329 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"torch/src/utils"
+)
+
+// Protocol versions in which login packets changed
+const (
+	// Version1_8 replaced the short length prefix of byte arrays with a VarInt
+	Version1_8 int32 = 47
+	// Version1_16 sends UUIDs in Login Success as binary instead of strings
+	Version1_16 int32 = 735
+	// Version1_19 added signature data to Login Start
+	Version1_19 int32 = 759
+	// Version1_19_1 added the optional UUID to Login Start
+	Version1_19_1 int32 = 760
+	// Version1_19_3 removed signature data from Login Start
+	Version1_19_3 int32 = 761
+	// Version1_20_2 made the UUID of Login Start mandatory
+	Version1_20_2 int32 = 764
+	// Version1_20_5 added Should Authenticate to Encryption Request
+	Version1_20_5 int32 = 766
+)
+
+// readLoginByteArray reads a byte array, which is prefixed by a short before 1.8
+func readLoginByteArray(r *bytes.Reader, protocol int32) ([]byte, error) {
+	if protocol >= Version1_8 {
+		return ReadByteArray(r)
+	}
+
+	length, err := ReadUnsignedShort(r)
+	if err != nil {
+		return nil, err
+	}
+	if int(length) > r.Len() {
+		return nil, fmt.Errorf("byte array: length %d exceeds remaining %d bytes", length, r.Len())
+	}
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+	return data, err
+}
+
+func writeLoginByteArray(val []byte, w *bytes.Buffer, protocol int32) error {
+	if protocol >= Version1_8 {
+		return WriteByteArray(val, w)
+	}
+
+	if err := WriteUnsignedShort(uint16(len(val)), w); err != nil {
+		return err
+	}
+	_, err := w.Write(val)
+	return err
+}
+
+// Disconnect is sent by the server to refuse the login
+type Disconnect struct {
+	// Reason is the JSON encoded text component
+	Reason []byte
+}
+
+func (p *Disconnect) ID() int32 {
+	return 0x00
+}
+
+func (p *Disconnect) Encode(w *bytes.Buffer, protocol int32) error {
+	return WriteByteArray(p.Reason, w)
+}
+
+func (p *Disconnect) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	p.Reason, err = utils.ReadString(r)
+	return err
+}
+
+// EncryptionRequest is sent by servers in online mode
+type EncryptionRequest struct {
+	ServerID           string
+	PublicKey          []byte
+	VerifyToken        []byte
+	ShouldAuthenticate bool
+}
+
+func (p *EncryptionRequest) ID() int32 {
+	return 0x01
+}
+
+func (p *EncryptionRequest) Encode(w *bytes.Buffer, protocol int32) error {
+	if err := utils.WriteString(p.ServerID, w); err != nil {
+		return err
+	}
+	if err := writeLoginByteArray(p.PublicKey, w, protocol); err != nil {
+		return err
+	}
+	if err := writeLoginByteArray(p.VerifyToken, w, protocol); err != nil {
+		return err
+	}
+	if protocol >= Version1_20_5 {
+		return WriteBool(p.ShouldAuthenticate, w)
+	}
+	return nil
+}
+
+func (p *EncryptionRequest) Decode(r *bytes.Reader, protocol int32) error {
+	serverId, err := utils.ReadString(r)
+	if err != nil {
+		return err
+	}
+	p.ServerID = string(serverId)
+	if p.PublicKey, err = readLoginByteArray(r, protocol); err != nil {
+		return err
+	}
+	if p.VerifyToken, err = readLoginByteArray(r, protocol); err != nil {
+		return err
+	}
+
+	// Servers before 1.20.5 always authenticate in online mode
+	p.ShouldAuthenticate = true
+	if protocol >= Version1_20_5 {
+		p.ShouldAuthenticate, err = ReadBool(r)
+	}
+	return err
+}
+
+// LoginSuccess is sent once the login has completed. Only the UUID and username are decoded, the
+// properties that follow in newer versions are ignored.
+type LoginSuccess struct {
+	UUID     UUID
+	Username string
+}
+
+func (p *LoginSuccess) ID() int32 {
+	return 0x02
+}
+
+func (p *LoginSuccess) Encode(w *bytes.Buffer, protocol int32) error {
+	if protocol >= Version1_16 {
+		if err := WriteUUID(p.UUID, w); err != nil {
+			return err
+		}
+	} else if err := utils.WriteString(p.UUID.String(), w); err != nil {
+		return err
+	}
+	if err := utils.WriteString(p.Username, w); err != nil {
+		return err
+	}
+	// No properties
+	if protocol >= Version1_19 {
+		if _, err := utils.WriteVarInt(0, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *LoginSuccess) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	if protocol >= Version1_16 {
+		if p.UUID, err = ReadUUID(r); err != nil {
+			return err
+		}
+	} else {
+		raw, err := utils.ReadString(r)
+		if err != nil {
+			return err
+		}
+		decoded, err := hex.DecodeString(strings.ReplaceAll(string(raw), "-", ""))
+		if err != nil || len(decoded) != len(p.UUID) {
+			return fmt.Errorf("invalid UUID %q", raw)
+		}
+		copy(p.UUID[:], decoded)
+	}
+
+	username, err := utils.ReadString(r)
+	if err != nil {
+		return err
+	}
+	p.Username = string(username)
+	return nil
+}
+
+// SetCompression enables compression for all following packets of the connection
+type SetCompression struct {
+	Threshold int32
+}
+
+func (p *SetCompression) ID() int32 {
+	return 0x03
+}
+
+func (p *SetCompression) Encode(w *bytes.Buffer, protocol int32) error {
+	_, err := utils.WriteVarInt(p.Threshold, w)
+	return err
+}
+
+func (p *SetCompression) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	p.Threshold, _, err = utils.ReadVarInt(r)
+	return err
+}
+
+// LoginPluginRequest is a custom query of the server, e.g. for Velocity modern forwarding
+type LoginPluginRequest struct {
+	MessageID int32
+	Channel   string
+	Data      []byte
+}
+
+func (p *LoginPluginRequest) ID() int32 {
+	return 0x04
+}
+
+func (p *LoginPluginRequest) Encode(w *bytes.Buffer, protocol int32) error {
+	if _, err := utils.WriteVarInt(p.MessageID, w); err != nil {
+		return err
+	}
+	if err := utils.WriteString(p.Channel, w); err != nil {
+		return err
+	}
+	_, err := w.Write(p.Data)
+	return err
+}
+
+func (p *LoginPluginRequest) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	if p.MessageID, _, err = utils.ReadVarInt(r); err != nil {
+		return err
+	}
+	channel, err := utils.ReadString(r)
+	if err != nil {
+		return err
+	}
+	p.Channel = string(channel)
+	p.Data = make([]byte, r.Len())
+	_, err = io.ReadFull(r, p.Data)
+	return err
+}
+
+// LoginStart begins the login with the given username
+type LoginStart struct {
+	Name string
+	// UUID is only sent from 1.20.2, where it is mandatory
+	UUID UUID
+}
+
+func (p *LoginStart) ID() int32 {
+	return 0x00
+}
+
+func (p *LoginStart) Encode(w *bytes.Buffer, protocol int32) error {
+	if err := utils.WriteString(p.Name, w); err != nil {
+		return err
+	}
+
+	switch {
+	case protocol >= Version1_20_2:
+		return WriteUUID(p.UUID, w)
+	// Has UUID
+	case protocol >= Version1_19_3:
+		return WriteBool(false, w)
+	// Has Sig Data, Has UUID
+	case protocol == Version1_19_1:
+		if err := WriteBool(false, w); err != nil {
+			return err
+		}
+		return WriteBool(false, w)
+	// Has Sig Data
+	case protocol == Version1_19:
+		return WriteBool(false, w)
+	}
+
+	return nil
+}
+
+// Decode reads the name and, from 1.20.2, the UUID. The optional fields of 1.19 - 1.20.1 are ignored.
+func (p *LoginStart) Decode(r *bytes.Reader, protocol int32) error {
+	name, err := utils.ReadString(r)
+	if err != nil {
+		return err
+	}
+	p.Name = string(name)
+	if protocol >= Version1_20_2 {
+		p.UUID, err = ReadUUID(r)
+	}
+	return err
+}
+
+// LoginPluginResponse answers a LoginPluginRequest, Data is only sent when Successful is set
+type LoginPluginResponse struct {
+	MessageID  int32
+	Successful bool
+	Data       []byte
+}
+
+func (p *LoginPluginResponse) ID() int32 {
+	return 0x02
+}
+
+func (p *LoginPluginResponse) Encode(w *bytes.Buffer, protocol int32) error {
+	if _, err := utils.WriteVarInt(p.MessageID, w); err != nil {
+		return err
+	}
+	if err := WriteBool(p.Successful, w); err != nil {
+		return err
+	}
+	if p.Successful {
+		_, err := w.Write(p.Data)
+		return err
+	}
+	return nil
+}
+
+func (p *LoginPluginResponse) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	if p.MessageID, _, err = utils.ReadVarInt(r); err != nil {
+		return err
+	}
+	if p.Successful, err = ReadBool(r); err != nil {
+		return err
+	}
+	p.Data = make([]byte, r.Len())
+	_, err = io.ReadFull(r, p.Data)
+	return err
+}
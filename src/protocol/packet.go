@@ -0,0 +1,94 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// State is the connection state that determines how packet IDs are interpreted
+type State int32
+
+const (
+	StateHandshaking State = 0
+	StateStatus      State = 1
+	StateLogin       State = 2
+)
+
+func (s State) String() string {
+	switch s {
+	case StateHandshaking:
+		return "handshaking"
+	case StateStatus:
+		return "status"
+	case StateLogin:
+		return "login"
+	default:
+		return fmt.Sprintf("state(%d)", int32(s))
+	}
+}
+
+// Packet is a single packet of the Java Edition protocol. The protocol version is passed to
+// Encode and Decode for packets whose layout changed between versions.
+type Packet interface {
+	// ID returns the packet ID within its state
+	ID() int32
+	// Encode writes the packet fields, without the length and packet ID
+	Encode(w *bytes.Buffer, protocol int32) error
+	// Decode reads the packet fields, after the packet ID
+	Decode(r *bytes.Reader, protocol int32) error
+}
+
+// Registry maps the packet IDs of each state to a constructor of the packet
+type Registry map[State]map[int32]func() Packet
+
+// Register adds a packet constructor for the given state
+func (r Registry) Register(state State, new func() Packet) {
+	if r[state] == nil {
+		r[state] = make(map[int32]func() Packet)
+	}
+	r[state][new().ID()] = new
+}
+
+// New returns an empty packet for the state and ID, or nil if it is not registered
+func (r Registry) New(state State, id int32) Packet {
+	if new, ok := r[state][id]; ok {
+		return new()
+	}
+	return nil
+}
+
+// Clientbound contains the packets sent by a server, used when reading packets
+var Clientbound = Registry{}
+
+func init() {
+	Clientbound.Register(StateStatus, func() Packet { return &StatusResponse{} })
+	Clientbound.Register(StateStatus, func() Packet { return &PongResponse{} })
+
+	Clientbound.Register(StateLogin, func() Packet { return &Disconnect{} })
+	Clientbound.Register(StateLogin, func() Packet { return &EncryptionRequest{} })
+	Clientbound.Register(StateLogin, func() Packet { return &LoginSuccess{} })
+	Clientbound.Register(StateLogin, func() Packet { return &SetCompression{} })
+	Clientbound.Register(StateLogin, func() Packet { return &LoginPluginRequest{} })
+}
+
+// UnknownPacket is returned when reading a packet ID that is not registered for the current state
+type UnknownPacket struct {
+	PacketID int32
+	Data     []byte
+}
+
+func (p *UnknownPacket) ID() int32 {
+	return p.PacketID
+}
+
+func (p *UnknownPacket) Encode(w *bytes.Buffer, protocol int32) error {
+	_, err := w.Write(p.Data)
+	return err
+}
+
+func (p *UnknownPacket) Decode(r *bytes.Reader, protocol int32) error {
+	p.Data = make([]byte, r.Len())
+	_, err := io.ReadFull(r, p.Data)
+	return err
+}
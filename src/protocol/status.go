@@ -0,0 +1,76 @@
+package protocol
+
+import (
+	"bytes"
+
+	"torch/src/utils"
+)
+
+type StatusRequest struct{}
+
+func (p *StatusRequest) ID() int32 {
+	return 0x00
+}
+
+func (p *StatusRequest) Encode(w *bytes.Buffer, protocol int32) error {
+	return nil
+}
+
+func (p *StatusRequest) Decode(r *bytes.Reader, protocol int32) error {
+	return nil
+}
+
+// StatusResponse contains the JSON encoded server list ping response
+type StatusResponse struct {
+	JSON []byte
+}
+
+func (p *StatusResponse) ID() int32 {
+	return 0x00
+}
+
+func (p *StatusResponse) Encode(w *bytes.Buffer, protocol int32) error {
+	return WriteByteArray(p.JSON, w)
+}
+
+func (p *StatusResponse) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	p.JSON, err = utils.ReadString(r)
+	return err
+}
+
+type PingRequest struct {
+	Payload int64
+}
+
+func (p *PingRequest) ID() int32 {
+	return 0x01
+}
+
+func (p *PingRequest) Encode(w *bytes.Buffer, protocol int32) error {
+	return WriteLong(p.Payload, w)
+}
+
+func (p *PingRequest) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	p.Payload, err = ReadLong(r)
+	return err
+}
+
+type PongResponse struct {
+	Payload int64
+}
+
+func (p *PongResponse) ID() int32 {
+	return 0x01
+}
+
+func (p *PongResponse) Encode(w *bytes.Buffer, protocol int32) error {
+	return WriteLong(p.Payload, w)
+}
+
+func (p *PongResponse) Decode(r *bytes.Reader, protocol int32) error {
+	var err error
+	p.Payload, err = ReadLong(r)
+	return err
+}
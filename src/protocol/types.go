@@ -0,0 +1,136 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"torch/src/utils"
+)
+
+// UUID is a 128-bit UUID sent as two big-endian longs
+type UUID [16]byte
+
+// String returns the UUID in its hyphenated form
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
+
+func ReadVarLong(r io.Reader) (int64, int, error) {
+	byteReader := utils.ByteReader(r)
+
+	var numRead int = 0
+	var result int64 = 0
+	for {
+		data, err := byteReader.ReadByte()
+		if err != nil {
+			return 0, numRead, err
+		}
+		value := (data & 0b01111111)
+		result |= int64(value) << (7 * numRead)
+		numRead++
+		if numRead > 10 {
+			return 0, numRead, errors.New("varlong: attempted to read an oversized varlong")
+		}
+		if (data & 0b10000000) == 0 {
+			break
+		}
+	}
+	return result, numRead, nil
+}
+
+func WriteVarLong(val int64, w io.Writer) (int, error) {
+	var data [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(data[:], uint64(val))
+	return w.Write(data[:n])
+}
+
+func ReadUUID(r io.Reader) (UUID, error) {
+	var uuid UUID
+	_, err := io.ReadFull(r, uuid[:])
+	return uuid, err
+}
+
+func WriteUUID(val UUID, w io.Writer) error {
+	_, err := w.Write(val[:])
+	return err
+}
+
+func ReadUnsignedShort(r io.Reader) (uint16, error) {
+	var val uint16
+	err := binary.Read(r, binary.BigEndian, &val)
+	return val, err
+}
+
+func WriteUnsignedShort(val uint16, w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, val)
+}
+
+func ReadLong(r io.Reader) (int64, error) {
+	var val int64
+	err := binary.Read(r, binary.BigEndian, &val)
+	return val, err
+}
+
+func WriteLong(val int64, w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, val)
+}
+
+func ReadUnsignedLong(r io.Reader) (uint64, error) {
+	var val uint64
+	err := binary.Read(r, binary.BigEndian, &val)
+	return val, err
+}
+
+func WriteUnsignedLong(val uint64, w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, val)
+}
+
+func ReadBool(r io.Reader) (bool, error) {
+	data, err := utils.ByteReader(r).ReadByte()
+	if err != nil {
+		return false, err
+	}
+	switch data {
+	case 0x00:
+		return false, nil
+	case 0x01:
+		return true, nil
+	default:
+		return false, fmt.Errorf("bool: invalid value 0x%02x", data)
+	}
+}
+
+func WriteBool(val bool, w io.Writer) error {
+	data := []byte{0x00}
+	if val {
+		data[0] = 0x01
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// ReadByteArray reads a VarInt-prefixed byte array, with the same bounds as utils.ReadString
+func ReadByteArray(r io.Reader) ([]byte, error) {
+	return utils.ReadString(r)
+}
+
+func WriteByteArray(val []byte, w io.Writer) error {
+	if _, err := utils.WriteVarInt(int32(len(val)), w); err != nil {
+		return err
+	}
+	_, err := w.Write(val)
+	return err
+}
@@ -0,0 +1,126 @@
+package protocol
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestVarLongRoundTrip(t *testing.T) {
+	tests := []struct {
+		value  int64
+		length int
+	}{
+		{0, 1},
+		{1, 1},
+		{127, 1},
+		{128, 2},
+		{math.MaxInt32, 5},
+		{math.MaxInt64, 9},
+		{-1, 10},
+		{math.MinInt64, 10},
+	}
+
+	for _, test := range tests {
+		buf := &bytes.Buffer{}
+		n, err := WriteVarLong(test.value, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != test.length {
+			t.Errorf("WriteVarLong(%d) wrote %d bytes, want %d", test.value, n, test.length)
+		}
+
+		value, read, err := ReadVarLong(buf)
+		if err != nil || value != test.value || read != test.length {
+			t.Errorf("ReadVarLong = %d, %d, %v, want %d, %d", value, read, err, test.value, test.length)
+		}
+	}
+}
+
+func TestReadVarLongRejectsOverlong(t *testing.T) {
+	// Eleven bytes with the continuation bit set on all but the last
+	data := append(bytes.Repeat([]byte{0x80}, 10), 0x01)
+
+	if _, _, err := ReadVarLong(bytes.NewReader(data)); err == nil {
+		t.Error("read an 11 byte varlong")
+	}
+}
+
+func TestReadVarLongTruncated(t *testing.T) {
+	if _, _, err := ReadVarLong(bytes.NewReader([]byte{0x80, 0x80})); err == nil {
+		t.Error("read a varlong without its last byte")
+	}
+}
+
+func TestUUIDRoundTrip(t *testing.T) {
+	want := UUID{0x06, 0x9a, 0x79, 0xf4, 0x44, 0xe9, 0x47, 0x26, 0xa5, 0xbe, 0xfc, 0xa9, 0x0e, 0x38, 0xaa, 0xf5}
+
+	buf := &bytes.Buffer{}
+	if err := WriteUUID(want, buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 16 {
+		t.Errorf("wrote %d bytes, want 16", buf.Len())
+	}
+
+	uuid, err := ReadUUID(buf)
+	if err != nil || uuid != want {
+		t.Errorf("ReadUUID = %v, %v, want %v", uuid, err, want)
+	}
+	if s := uuid.String(); s != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Errorf("String() = %s", s)
+	}
+
+	if _, err := ReadUUID(bytes.NewReader(want[:15])); err == nil {
+		t.Error("read a UUID of 15 bytes")
+	}
+}
+
+func TestBoolRoundTrip(t *testing.T) {
+	for _, want := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := WriteBool(want, buf); err != nil {
+			t.Fatal(err)
+		}
+		value, err := ReadBool(buf)
+		if err != nil || value != want {
+			t.Errorf("ReadBool = %v, %v, want %v", value, err, want)
+		}
+	}
+
+	if _, err := ReadBool(bytes.NewReader([]byte{0x02})); err == nil {
+		t.Error("read 0x02 as a bool")
+	}
+}
+
+func TestUnsignedLongRoundTrip(t *testing.T) {
+	for _, want := range []uint64{0, 1, math.MaxInt64 + 1, math.MaxUint64} {
+		buf := &bytes.Buffer{}
+		if err := WriteUnsignedLong(want, buf); err != nil {
+			t.Fatal(err)
+		}
+		value, err := ReadUnsignedLong(buf)
+		if err != nil || value != want {
+			t.Errorf("ReadUnsignedLong = %d, %v, want %d", value, err, want)
+		}
+	}
+}
+
+func TestByteArrayRoundTrip(t *testing.T) {
+	for _, want := range [][]byte{{}, {0x00, 0xff}, bytes.Repeat([]byte{0x2a}, 300)} {
+		buf := &bytes.Buffer{}
+		if err := WriteByteArray(want, buf); err != nil {
+			t.Fatal(err)
+		}
+		value, err := ReadByteArray(buf)
+		if err != nil || !bytes.Equal(value, want) {
+			t.Errorf("ReadByteArray = %x, %v, want %x", value, err, want)
+		}
+	}
+
+	// A length prefix of 5 followed by 2 bytes
+	if _, err := ReadByteArray(bytes.NewReader([]byte{0x05, 0x01, 0x02})); err == nil {
+		t.Error("read a byte array shorter than its length")
+	}
+}
@@ -0,0 +1,15 @@
+package structs
+
+// Connection attempt states of a resolved address
+const (
+	AddressConnected    = "connected"
+	AddressFailed       = "failed"
+	AddressCancelled    = "cancelled"
+	AddressNotAttempted = "not_attempted"
+)
+
+type AddressResult struct {
+	IP    string `json:"ip"`
+	State string `json:"state"`
+	Error string `json:"error"`
+}
@@ -0,0 +1,26 @@
+package structs
+
+// Cache states of a result
+const (
+	// CacheFresh means the result was looked up for the request
+	CacheFresh = "fresh"
+	// CacheCached means the result was cached and has not expired
+	CacheCached = "cached"
+	// CacheStale means the result has expired and is being refreshed in the background
+	CacheStale = "stale"
+)
+
+// CacheMeta is embedded in cached results to report how they were obtained
+type CacheMeta struct {
+	CacheStatus string `json:"cache_status"`
+}
+
+// SetCacheStatus sets the cache state of the result, which must be a copy of the cached one
+func (m *CacheMeta) SetCacheStatus(status string) {
+	m.CacheStatus = status
+}
+
+// GetCacheStatus returns the cache state set with SetCacheStatus
+func (m *CacheMeta) GetCacheStatus() string {
+	return m.CacheStatus
+}
@@ -0,0 +1,13 @@
+package structs
+
+import "time"
+
+type Dns struct {
+	Host       string    `json:"host"`
+	CNAMEs     []string  `json:"cnames"`
+	A          []string  `json:"a"`
+	AAAA       []string  `json:"aaaa"`
+	TTL        uint32    `json:"ttl"`
+	ObtainedAt time.Time `json:"obtained_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
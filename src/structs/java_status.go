@@ -16,7 +16,7 @@ type RawJavaStatus struct {
 		} `json:"sample"`
 	} `json:"players"`
 	Description interface{} `json:"description"`
-	Favicon        string      `json:"favicon"`
+	Favicon     string      `json:"favicon"`
 	ModInfo     struct {
 		List []struct {
 			ModID   string `json:"modid"`
@@ -35,6 +35,8 @@ type RawJavaStatus struct {
 			ModID   string `json:"modId"`
 			Version string `json:"version"`
 		} `json:"mods"`
+		Truncated bool   `json:"truncated"`
+		D         string `json:"d"`
 	} `json:"forgeData"`
 }
 
@@ -55,8 +57,17 @@ type Players struct {
 }
 
 type ModInfo struct {
-	Type    string `json:"type"`
-	ModList []Mod  `json:"modList"`
+	Type              string    `json:"type"`
+	ModList           []Mod     `json:"modList"`
+	Channels          []Channel `json:"channels"`
+	FMLNetworkVersion int       `json:"fml_network_version"`
+	Truncated         bool      `json:"truncated"`
+}
+
+type Channel struct {
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Required bool   `json:"required"`
 }
 
 type Mod struct {
@@ -65,26 +76,54 @@ type Mod struct {
 }
 
 type SrvRecord struct {
-	Host string `json:"host"`
-	Port uint16 `json:"port"`
+	Host    string      `json:"host"`
+	Port    uint16      `json:"port"`
+	Records []SrvTarget `json:"records"`
 }
 
 type JavaStatus struct {
-	Host        string        `json:"host"`
-	Port        uint16        `json:"port"`
-	Version     Version       `json:"version"`
-	Players     Players       `json:"players"`
-	Description *ParsedText   `json:"description"`
-	Icon        string        `json:"icon"`
-	ModInfo     *ModInfo      `json:"mod_info"`
-	SrvRecord   *SrvRecord    `json:"used_srv"`
-	Latency     time.Duration `json:"latency"`
-	ObtainedAt  time.Time     `json:"obtained_at"`
-	ExpiresAt   time.Time     `json:"expires_at"`
+	Host        string          `json:"host"`
+	Port        uint16          `json:"port"`
+	Version     Version         `json:"version"`
+	Players     Players         `json:"players"`
+	Description *ParsedText     `json:"description"`
+	Icon        string          `json:"icon"`
+	ModInfo     *ModInfo        `json:"mod_info"`
+	SrvRecord   *SrvRecord      `json:"used_srv"`
+	PingVersion string          `json:"ping_version"`
+	IP          string          `json:"ip"`
+	Addresses   []AddressResult `json:"addresses"`
+	Software    *Software       `json:"software"`
+	Latency     Latency         `json:"latency"`
+	Timings     Timings         `json:"timings"`
+	Attempts    int             `json:"attempts"`
+	ObtainedAt  time.Time       `json:"obtained_at"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+
+	CacheMeta
 }
 
+// Server list ping variants, from newest to oldest
+const (
+	// PingVersionNetty is the status protocol used since 1.7
+	PingVersionNetty = "netty"
+	// PingVersionLegacy is the 0xFE 0x01 ping with the MC|PingHost plugin message (1.4 - 1.6)
+	PingVersionLegacy = "legacy"
+	// PingVersionBeta is the bare 0xFE ping (beta 1.8 - 1.3)
+	PingVersionBeta = "beta"
+)
+
 type OfflineServer struct {
 	Offline bool   `json:"offline"`
 	Host    string `json:"host"`
 	Port    uint16 `json:"port"`
+	// Error is the reason the last lookup failed
+	Error string `json:"error"`
+	// Failures is the number of consecutive failed lookups
+	Failures int `json:"failures"`
+	// LastOnline is when the server was last found online, if that is still known
+	LastOnline *time.Time `json:"last_online"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+
+	CacheMeta
 }
@@ -0,0 +1,65 @@
+package structs
+
+import (
+	"math"
+	"time"
+)
+
+// Latency summarizes the round trip times of repeated pings, in milliseconds
+type Latency struct {
+	Samples int     `json:"samples"`
+	MinMs   float64 `json:"min_ms"`
+	AvgMs   float64 `json:"avg_ms"`
+	MaxMs   float64 `json:"max_ms"`
+	// JitterMs is the mean difference between consecutive samples
+	JitterMs float64 `json:"jitter_ms"`
+}
+
+// Timings breaks a lookup down into its phases, in milliseconds. Phases that were not part of the
+// lookup are 0.
+type Timings struct {
+	DNSMs     float64 `json:"dns_ms"`
+	ConnectMs float64 `json:"connect_ms"`
+	// StatusMs is the time until the status was received, for Bedrock the first pong
+	StatusMs float64 `json:"status_ms"`
+	// PingMs is the time spent on the latency samples after the status was received
+	PingMs float64 `json:"ping_ms"`
+}
+
+// Milliseconds converts d to fractional milliseconds
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// NewLatency summarizes the round trip times in samples
+func NewLatency(samples []time.Duration) Latency {
+	latency := Latency{Samples: len(samples)}
+	if len(samples) == 0 {
+		return latency
+	}
+
+	min, max := samples[0], samples[0]
+	var sum, jitter time.Duration
+	for i, sample := range samples {
+		if sample < min {
+			min = sample
+		}
+		if sample > max {
+			max = sample
+		}
+		sum += sample
+
+		if i > 0 {
+			jitter += time.Duration(math.Abs(float64(sample - samples[i-1])))
+		}
+	}
+
+	latency.MinMs = Milliseconds(min)
+	latency.MaxMs = Milliseconds(max)
+	latency.AvgMs = Milliseconds(sum) / float64(len(samples))
+	if len(samples) > 1 {
+		latency.JitterMs = Milliseconds(jitter) / float64(len(samples)-1)
+	}
+
+	return latency
+}
@@ -0,0 +1,29 @@
+package structs
+
+import "time"
+
+// Login check results
+const (
+	// LoginResultOnlineMode means the server sent an Encryption Request and authenticates players with Mojang
+	LoginResultOnlineMode = "online_mode"
+	// LoginResultOfflineMode means the server accepted the login without authentication
+	LoginResultOfflineMode = "offline_mode"
+	// LoginResultDisconnected means the server refused the login, see Reason
+	LoginResultDisconnected = "disconnected"
+	// LoginResultUnsupported means the server is older than 1.7, whose login is not checked
+	LoginResultUnsupported = "unsupported"
+)
+
+type LoginCheck struct {
+	Host        string      `json:"host"`
+	Port        uint16      `json:"port"`
+	Protocol    int         `json:"protocol"`
+	Result      string      `json:"result"`
+	OnlineMode  *bool       `json:"online_mode"`
+	Whitelisted bool        `json:"whitelisted"`
+	Reason      *ParsedText `json:"reason"`
+	ObtainedAt  time.Time   `json:"obtained_at"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+
+	CacheMeta
+}
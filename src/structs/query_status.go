@@ -0,0 +1,29 @@
+package structs
+
+import "time"
+
+type QueryPlayers struct {
+	Max    int      `json:"max"`
+	Online int      `json:"online"`
+	List   []string `json:"list"`
+}
+
+type QueryStatus struct {
+	Host       string       `json:"host"`
+	Port       uint16       `json:"port"`
+	MOTD       *ParsedText  `json:"motd"`
+	GameType   string       `json:"game_type"`
+	GameID     string       `json:"game_id"`
+	Version    string       `json:"version"`
+	Software   string       `json:"software"`
+	Plugins    []string     `json:"plugins"`
+	Map        string       `json:"map"`
+	Players    QueryPlayers `json:"players"`
+	HostIP     string       `json:"host_ip"`
+	HostPort   uint16       `json:"host_port"`
+	Latency    Latency      `json:"latency"`
+	ObtainedAt time.Time    `json:"obtained_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+
+	CacheMeta
+}
@@ -0,0 +1,9 @@
+package structs
+
+type Software struct {
+	Name       string  `json:"name"`
+	Version    string  `json:"version"`
+	Family     string  `json:"family"`
+	Confidence float64 `json:"confidence"`
+	IsProxy    bool    `json:"is_proxy"`
+}
@@ -2,9 +2,17 @@ package structs
 
 import "time"
 
+type SrvTarget struct {
+	Target   string `json:"target"`
+	Port     uint16 `json:"port"`
+	Priority uint16 `json:"priority"`
+	Weight   uint16 `json:"weight"`
+}
+
 type Srv struct {
-	Target     string    `json:"target"`
-	Port       uint16    `json:"port"`
-	ObtainedAt time.Time `json:"obtained_at"`
-	ExpiresAt  time.Time `json:"expires_at"`
+	Target     string      `json:"target"`
+	Port       uint16      `json:"port"`
+	Records    []SrvTarget `json:"records"`
+	ObtainedAt time.Time   `json:"obtained_at"`
+	ExpiresAt  time.Time   `json:"expires_at"`
 }
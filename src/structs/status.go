@@ -0,0 +1,23 @@
+package structs
+
+// Editions answering a unified status request
+const (
+	EditionJava    = "java"
+	EditionBedrock = "bedrock"
+	EditionBoth    = "both"
+	EditionNone    = "none"
+)
+
+type Crossplay struct {
+	SameNetwork bool     `json:"same_network"`
+	Confidence  float64  `json:"confidence"`
+	Signals     []string `json:"signals"`
+}
+
+type Status struct {
+	Host      string         `json:"host"`
+	Edition   string         `json:"edition"`
+	Java      *JavaStatus    `json:"java"`
+	Bedrock   *BedrockStatus `json:"bedrock"`
+	Crossplay *Crossplay     `json:"crossplay"`
+}
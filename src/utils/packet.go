@@ -2,9 +2,20 @@ package utils
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
 )
 
+var (
+	// MaxPacketSize is the largest packet length in bytes accepted by ReadPacket
+	MaxPacketSize int32 = 2 * 1024 * 1024
+	// MaxStringSize is the largest string length in bytes accepted by ReadString
+	MaxStringSize int32 = 1024 * 1024
+)
+
+var errEmptyPacket = errors.New("packet: empty packet")
+
 func WritePacket(data *bytes.Buffer, writer io.Writer) error {
 	if _, err := WriteVarInt(int32(data.Len()), writer); err != nil {
 		return err
@@ -12,3 +23,38 @@ func WritePacket(data *bytes.Buffer, writer io.Writer) error {
 	_, err := io.Copy(writer, data)
 	return err
 }
+
+// ReadPacket reads a length-prefixed packet in full, rejecting lengths that are not positive or exceed MaxPacketSize
+func ReadPacket(r io.Reader) (*bytes.Reader, error) {
+	data, err := ReadPacketBuffer(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// ReadPacketBuffer reads a packet like ReadPacket into buf, which is only reallocated when it is too small
+func ReadPacketBuffer(r io.Reader, buf []byte) ([]byte, error) {
+	length, _, err := ReadVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if length == 0 {
+		return nil, errEmptyPacket
+	}
+
+	if length < 0 || length > MaxPacketSize {
+		return nil, fmt.Errorf("packet: length %d out of range (maximum %d)", length, MaxPacketSize)
+	}
+
+	if cap(buf) < int(length) {
+		buf = make([]byte, length)
+	}
+	buf = buf[:length]
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
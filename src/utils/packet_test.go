@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzReadPacketBuffer(f *testing.F) {
+	// A packet of 3 bytes
+	f.Add([]byte{0x03, 0x01, 0x02, 0x03})
+	// Empty packet
+	f.Add([]byte{0x00})
+	// Negative length (-1)
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F})
+	// Huge length (2147483647), which used to be allocated before reading
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x07, 0x00})
+	// Length just above MaxPacketSize
+	f.Add([]byte{0x81, 0x80, 0x80, 0x01})
+	// Length longer than the data
+	f.Add([]byte{0x10, 0x01})
+	// Oversized VarInt
+	f.Add([]byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		packet, err := ReadPacketBuffer(bytes.NewReader(data), nil)
+		if err != nil {
+			return
+		}
+
+		if len(packet) < 1 || int32(len(packet)) > MaxPacketSize {
+			t.Fatalf("read a packet of %d bytes", len(packet))
+		}
+		if len(packet) >= len(data) {
+			t.Fatalf("read a packet of %d bytes from %d bytes of data", len(packet), len(data))
+		}
+	})
+}
@@ -1,14 +1,23 @@
 package utils
 
 import (
+	"fmt"
 	"io"
 )
 
+// ReadString reads a VarInt-prefixed string, rejecting lengths that are negative, exceed MaxStringSize
+// or exceed the remaining data when the reader knows its length
 func ReadString(r io.Reader) ([]byte, error) {
 	length, _, err := ReadVarInt(r)
 	if err != nil {
 		return nil, err
 	}
+	if length < 0 || length > MaxStringSize {
+		return nil, fmt.Errorf("string: length %d out of range (maximum %d)", length, MaxStringSize)
+	}
+	if sized, ok := r.(interface{ Len() int }); ok && int(length) > sized.Len() {
+		return nil, fmt.Errorf("string: length %d exceeds remaining %d bytes", length, sized.Len())
+	}
 	data := make([]byte, length)
 	if _, err := io.ReadFull(r, data); err != nil {
 		return nil, err
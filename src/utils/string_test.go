@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzReadString(f *testing.F) {
+	f.Add([]byte{0x05, 'h', 'e', 'l', 'l', 'o'})
+	f.Add([]byte{0x00})
+	// Negative length (-1)
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x0F})
+	// Huge length (2147483647)
+	f.Add([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x07})
+	// Length longer than the remaining data
+	f.Add([]byte{0x7F, 'h', 'i'})
+	// Truncated VarInt
+	f.Add([]byte{0x80})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		reader := bytes.NewReader(data)
+		value, err := ReadString(reader)
+		if err != nil {
+			return
+		}
+
+		if int32(len(value)) > MaxStringSize || len(value) >= len(data) {
+			t.Fatalf("read a string of %d bytes from %d bytes of data", len(value), len(data))
+		}
+
+		consumed := len(data) - reader.Len()
+		if !bytes.Equal(data[consumed-len(value):consumed], value) {
+			t.Fatalf("string %q is not the data after its length", value)
+		}
+	})
+}
@@ -1,47 +1,63 @@
 package utils
 
 import (
+	"encoding/binary"
 	"errors"
 	"io"
 )
 
+// ReadVarInt reads a VarInt, byte by byte through ReadByte when r implements io.ByteReader
 func ReadVarInt(r io.Reader) (int32, int, error) {
+	byteReader := ByteReader(r)
+
 	var numRead int = 0
 	var result int32 = 0
 	for {
-		data := make([]byte, 1)
-		n, err := r.Read(data)
+		data, err := byteReader.ReadByte()
 		if err != nil {
 			return 0, numRead, err
 		}
-		if n < 1 {
-			return 0, numRead, io.EOF
-		}
-		value := (data[0] & 0b01111111)
+		value := (data & 0b01111111)
 		result |= int32(value) << (7 * numRead)
 		numRead++
 		if numRead > 5 {
 			return 0, numRead, errors.New("varint: attempted to read an oversized varint")
 		}
-		if (data[0] & 0b10000000) == 0 {
+		if (data & 0b10000000) == 0 {
 			break
 		}
 	}
 	return result, numRead, nil
 }
 
-func WriteVarInt(val int32, w io.Writer) (int, error) {
-	var numWritten int = 0
-	for {
-		if (uint32(val) & 0xFFFFFF80) == 0 {
-			_, err := w.Write([]byte{byte(val)})
-			numWritten++
-			return numWritten, err
-		}
-		_, err := w.Write([]byte{byte(val&0x7F | 0x80)})
-		if err != nil {
-			return numWritten, err
-		}
-		val = int32(uint32(val) >> 7)
+// ByteReader returns r if it implements io.ByteReader, otherwise a wrapper reading one byte per Read call
+func ByteReader(r io.Reader) io.ByteReader {
+	if byteReader, ok := r.(io.ByteReader); ok {
+		return byteReader
+	}
+	return &singleByteReader{r: r}
+}
+
+// singleByteReader implements io.ByteReader for readers without buffering
+type singleByteReader struct {
+	r    io.Reader
+	data [1]byte
+}
+
+func (s *singleByteReader) ReadByte() (byte, error) {
+	n, err := s.r.Read(s.data[:])
+	if n == 1 {
+		return s.data[0], nil
 	}
+	if err == nil {
+		err = io.EOF
+	}
+	return 0, err
+}
+
+// WriteVarInt encodes the VarInt on the stack and writes it with a single Write call
+func WriteVarInt(val int32, w io.Writer) (int, error) {
+	var data [binary.MaxVarintLen32]byte
+	n := binary.PutUvarint(data[:], uint64(uint32(val)))
+	return w.Write(data[:n])
 }
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+// readVarIntUnbuffered is ReadVarInt before it used io.ByteReader, allocating and calling Read for
+// every byte, kept to benchmark against
+func readVarIntUnbuffered(r io.Reader) (int32, int, error) {
+	var numRead int = 0
+	var result int32 = 0
+	for {
+		data := make([]byte, 1)
+		n, err := r.Read(data)
+		if err != nil {
+			return 0, numRead, err
+		}
+		if n < 1 {
+			return 0, numRead, io.EOF
+		}
+		value := (data[0] & 0b01111111)
+		result |= int32(value) << (7 * numRead)
+		numRead++
+		if numRead > 5 {
+			return 0, numRead, errors.New("varint: attempted to read an oversized varint")
+		}
+		if (data[0] & 0b10000000) == 0 {
+			break
+		}
+	}
+	return result, numRead, nil
+}
+
+// readerOnly hides the io.ByteReader of the wrapped reader, like a net.Conn
+type readerOnly struct {
+	io.Reader
+}
+
+var varIntValues = []int32{0, 1, 127, 128, 25565, math.MaxInt32, -1, math.MinInt32}
+
+func encodeVarInts(values []int32) []byte {
+	buf := &bytes.Buffer{}
+	for _, value := range values {
+		WriteVarInt(value, buf)
+	}
+	return buf.Bytes()
+}
+
+func TestReadVarInt(t *testing.T) {
+	data := encodeVarInts(varIntValues)
+
+	byteReader := bytes.NewReader(data)
+	unbuffered := readerOnly{bytes.NewReader(data)}
+	for _, want := range varIntValues {
+		value, _, err := ReadVarInt(byteReader)
+		if err != nil || value != want {
+			t.Errorf("ReadVarInt = %d, %v, want %d", value, err, want)
+		}
+
+		value, _, err = ReadVarInt(unbuffered)
+		if err != nil || value != want {
+			t.Errorf("ReadVarInt without io.ByteReader = %d, %v, want %d", value, err, want)
+		}
+	}
+}
+
+func TestWriteVarIntLength(t *testing.T) {
+	tests := []struct {
+		value  int32
+		length int
+	}{
+		{0, 1},
+		{127, 1},
+		{128, 2},
+		{25565, 3},
+		{math.MaxInt32, 5},
+		{-1, 5},
+		{math.MinInt32, 5},
+	}
+
+	for _, test := range tests {
+		n, err := WriteVarInt(test.value, &bytes.Buffer{})
+		if err != nil || n != test.length {
+			t.Errorf("WriteVarInt(%d) = %d, %v, want %d", test.value, n, err, test.length)
+		}
+	}
+}
+
+func TestReadVarIntRejectsOverlong(t *testing.T) {
+	// Six bytes with the continuation bit set on all but the last
+	data := []byte{0x80, 0x80, 0x80, 0x80, 0x80, 0x01}
+
+	if _, _, err := ReadVarInt(bytes.NewReader(data)); err == nil {
+		t.Error("read a 6 byte varint")
+	}
+}
+
+func benchmarkReadVarInt(b *testing.B, newReader func(data []byte) io.Reader, read func(io.Reader) (int32, int, error)) {
+	data := encodeVarInts(varIntValues)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		r := newReader(data)
+		for range varIntValues {
+			if _, _, err := read(r); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkReadVarIntUnbuffered is the path before buffered readers, each Read of a net.Conn being a
+// system call makes the real difference larger
+func BenchmarkReadVarIntUnbuffered(b *testing.B) {
+	benchmarkReadVarInt(b, func(data []byte) io.Reader {
+		return readerOnly{bytes.NewReader(data)}
+	}, readVarIntUnbuffered)
+}
+
+func BenchmarkReadVarIntByteReader(b *testing.B) {
+	benchmarkReadVarInt(b, func(data []byte) io.Reader {
+		return bytes.NewReader(data)
+	}, ReadVarInt)
+}